@@ -0,0 +1,20 @@
+// Package abi embeds the abi files of the contracts the dumper indexes
+package abi
+
+import (
+	"embed"
+	"os"
+	"path/filepath"
+)
+
+//go:embed *.abi
+var files embed.FS
+
+// Read returns the abi of the contract, e.g.(LicenseNFT), from the directory if it is set,
+// otherwise the one built into the binary
+func Read(dir string, contract string) ([]byte, error) {
+	if dir != "" {
+		return os.ReadFile(filepath.Join(dir, contract+".abi"))
+	}
+	return files.ReadFile(contract + ".abi")
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"gorm.io/gorm"
+
+	"github.com/Me-Nodeslist/database/database"
+)
+
+var FailedCmd = &cli.Command{
+	Name:  "failed",
+	Usage: "inspect and retry the events whose handlers failed",
+	Subcommands: []*cli.Command{
+		failedListCmd,
+		failedRetryCmd,
+	},
+}
+
+var failedListCmd = &cli.Command{
+	Name:  "list",
+	Usage: "list the failed events, newest first",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "chain",
+			Usage: "input chain name, e.g.(dev)",
+			Value: "product",
+		},
+		&cli.BoolFlag{
+			Name:  "resolved",
+			Usage: "list the events that succeeded on a retry instead of the unresolved ones",
+			Value: false,
+		},
+		&cli.IntFlag{
+			Name:  "offset",
+			Usage: "input paging start index",
+			Value: 0,
+		},
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "input number of events to list",
+			Value: 20,
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		err := database.InitDatabase("~/.nodedelegation-" + ctx.String("chain"))
+		if err != nil {
+			return err
+		}
+
+		infos, err := database.GetFailedEvents(ctx.Bool("resolved"), ctx.Int("offset"), ctx.Int("limit"))
+		if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			fmt.Printf("%d\t%s\tblock %d\ttx %s\tlog %d\tattempts %d\tnext retry %s\n", info.ID, info.EventName, info.BlockNumber, info.TxHash, info.LogIndex, info.Attempts, time.Unix(info.NextRetry, 0).Format("2006-01-02 15:04:05"))
+			fmt.Printf("\t%s\n", info.Error)
+		}
+		return nil
+	},
+}
+
+var failedRetryCmd = &cli.Command{
+	Name:      "retry",
+	Usage:     "retry a failed event in the next dump cycle of the running server, which rolls back to its block and replays it and all later events",
+	ArgsUsage: "<id>",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "chain",
+			Usage: "input chain name, e.g.(dev)",
+			Value: "product",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		var id uint
+		_, err := fmt.Sscan(ctx.Args().First(), &id)
+		if err != nil {
+			return errors.New("input the id of a failed event")
+		}
+
+		err = database.InitDatabase("~/.nodedelegation-" + ctx.String("chain"))
+		if err != nil {
+			return err
+		}
+
+		err = database.RetryFailedEventNow(id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("no unresolved failed event %d", id)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("failed event %d will be retried in the next dump cycle\n", id)
+		return nil
+	},
+}
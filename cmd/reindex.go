@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/urfave/cli/v2"
+
+	"github.com/Me-Nodeslist/database/database"
+	"github.com/Me-Nodeslist/database/dumper"
+)
+
+var ReindexCmd = &cli.Command{
+	Name:  "reindex",
+	Usage: "rebuild the derived tables from a block, it refuses to run while the node-delegation server is running",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "chain",
+			Usage: "input chain name, e.g.(dev)",
+			Value: "product",
+		},
+		&cli.StringSliceFlag{
+			Name:  "ethrpc",
+			Usage: "input eth chain rpc urls, the first healthy one is used, e.g.(--ethrpc url1 --ethrpc url2)",
+			Value: cli.NewStringSlice("product"),
+		},
+		&cli.StringFlag{
+			Name:  "licenseNFT",
+			Usage: "input licenseNFT contract address",
+			Value: "",
+		},
+		&cli.StringFlag{
+			Name:  "delMEMO",
+			Usage: "input delMEMO contract address",
+			Value: "",
+		},
+		&cli.StringFlag{
+			Name:  "settlement",
+			Usage: "input settlement contract address",
+			Value: "",
+		},
+		&cli.StringFlag{
+			Name:  "delegation",
+			Usage: "input delegation contract address",
+			Value: "",
+		},
+		&cli.Uint64Flag{
+			Name:  "confirmations",
+			Usage: "input how many blocks to stay behind the latest block",
+			Value: 1,
+		},
+		&cli.StringFlag{
+			Name:  "blockTag",
+			Usage: "input which block to follow, e.g.(latest, safe, finalized)",
+			Value: "latest",
+		},
+		&cli.StringFlag{
+			Name:  "abiDir",
+			Usage: "input the directory of the contract abi files to use instead of the built-in ones",
+			Value: "",
+		},
+		&cli.DurationFlag{
+			Name:  "rpcTimeout",
+			Usage: "input the timeout of a single rpc call, e.g.(15s)",
+			Value: dumper.DEFAULT_RPC_TIMEOUT,
+		},
+		&cli.Int64Flag{
+			Name:     "from",
+			Usage:    "input the block to rebuild the derived tables from",
+			Required: true,
+		},
+		&cli.BoolFlag{
+			Name:  "rpc",
+			Usage: "replay events from the rpc instead of the local archive",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "haltOnFailure",
+			Usage: "stop dumping at an event whose handler fails instead of queueing it for retry",
+			Value: false,
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		chain := ctx.String("chain")
+		ethrpc := ctx.StringSlice("ethrpc")
+
+		opts := &dumper.Options{
+			Confirmations: ctx.Uint64("confirmations"),
+			BlockTag:      ctx.String("blockTag"),
+			RPCTimeout:    ctx.Duration("rpcTimeout"),
+			ABIDir:        ctx.String("abiDir"),
+			HaltOnFailure: ctx.Bool("haltOnFailure"),
+		}
+
+		addrs := &dumper.ContractAddress{
+			LicenseNFT: common.HexToAddress(ctx.String("licenseNFT")),
+			DelMEMO:    common.HexToAddress(ctx.String("delMEMO")),
+			Settlement: common.HexToAddress(ctx.String("settlement")),
+			Delegation: common.HexToAddress(ctx.String("delegation")),
+		}
+
+		err := database.InitDatabase("~/.nodedelegation-" + chain)
+		if err != nil {
+			return err
+		}
+
+		unlock, err := dumper.LockDatabase()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		d, err := dumper.NewDumper(ethrpc, addrs, opts)
+		if err != nil {
+			return err
+		}
+		defer d.Close()
+
+		return d.Reindex(ctx.Int64("from"), !ctx.Bool("rpc"))
+	},
+}
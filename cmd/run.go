@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/urfave/cli/v2"
@@ -31,10 +32,15 @@ var ServerRunCmd = &cli.Command{
 			Usage: "input chain name, e.g.(dev)",
 			Value: "product",
 		},
-		&cli.StringFlag{
+		&cli.StringSliceFlag{
 			Name:  "ethrpc",
-			Usage: "input eth chain rpc url",
-			Value: "product",
+			Usage: "input eth chain rpc urls, the first healthy one is used, e.g.(--ethrpc url1 --ethrpc url2)",
+			Value: cli.NewStringSlice("product"),
+		},
+		&cli.StringFlag{
+			Name:  "ethws",
+			Usage: "input eth chain websocket rpc url to follow new blocks in real time, polls when empty",
+			Value: "",
 		},
 		&cli.StringFlag{
 			Name:  "licenseNFT",
@@ -61,11 +67,61 @@ var ServerRunCmd = &cli.Command{
 			Usage: "input etherscan api key",
 			Value: "",
 		},
+		&cli.Uint64Flag{
+			Name:  "confirmations",
+			Usage: "input how many blocks to stay behind the latest block",
+			Value: 1,
+		},
+		&cli.StringFlag{
+			Name:  "blockTag",
+			Usage: "input which block to follow, e.g.(latest, safe, finalized)",
+			Value: "latest",
+		},
+		&cli.StringFlag{
+			Name:  "abiDir",
+			Usage: "input the directory of the contract abi files to use instead of the built-in ones",
+			Value: "",
+		},
+		&cli.DurationFlag{
+			Name:  "rpcTimeout",
+			Usage: "input the timeout of a single rpc call, e.g.(15s)",
+			Value: dumper.DEFAULT_RPC_TIMEOUT,
+		},
+		&cli.IntFlag{
+			Name:  "purchaseQuorum",
+			Usage: "input how many rpc endpoints must agree on the transaction and receipt of a license payment",
+			Value: 1,
+		},
+		&cli.Uint64Flag{
+			Name:  "chunkSize",
+			Usage: "input how many blocks to query logs for at once",
+			Value: dumper.DEFAULT_CHUNK_SIZE,
+		},
+		&cli.Uint64Flag{
+			Name:  "maxChunkSize",
+			Usage: "input the max blocks to query logs for at once when ranges are sparse",
+			Value: 10 * dumper.DEFAULT_CHUNK_SIZE,
+		},
+		&cli.DurationFlag{
+			Name:  "chunkInterval",
+			Usage: "input the pause between two log queries during backfill, e.g.(200ms)",
+			Value: 200 * time.Millisecond,
+		},
+		&cli.DurationFlag{
+			Name:  "reconcileInterval",
+			Usage: "input how often to check the DelMEMO balances against the contract, 0 disables it, e.g.(1h)",
+			Value: dumper.DEFAULT_RECONCILE_INTERVAL,
+		},
+		&cli.BoolFlag{
+			Name:  "haltOnFailure",
+			Usage: "stop dumping at an event whose handler fails instead of queueing it for retry",
+			Value: false,
+		},
 	},
 	Action: func(ctx *cli.Context) error {
 		endPoint := ctx.String("endpoint")
 		chain := ctx.String("chain")
-		ethrpc := ctx.String("ethrpc")
+		ethrpc := ctx.StringSlice("ethrpc")
 
 		licenseNFT := ctx.String("licenseNFT")
 		delMEMO := ctx.String("delMEMO")
@@ -74,6 +130,19 @@ var ServerRunCmd = &cli.Command{
 
 		apikey := ctx.String("apikey")
 
+		opts := &dumper.Options{
+			Confirmations:  ctx.Uint64("confirmations"),
+			BlockTag:       ctx.String("blockTag"),
+			ChunkSize:      ctx.Uint64("chunkSize"),
+			MaxChunkSize:   ctx.Uint64("maxChunkSize"),
+			ChunkInterval:  ctx.Duration("chunkInterval"),
+			RPCTimeout:     ctx.Duration("rpcTimeout"),
+			PurchaseQuorum: ctx.Int("purchaseQuorum"),
+			WSEndpoint:     ctx.String("ethws"),
+			ABIDir:         ctx.String("abiDir"),
+			HaltOnFailure:  ctx.Bool("haltOnFailure"),
+		}
+
 		addrs := &dumper.ContractAddress{
 			LicenseNFT: common.HexToAddress(licenseNFT),
 			DelMEMO:    common.HexToAddress(delMEMO),
@@ -89,17 +158,17 @@ var ServerRunCmd = &cli.Command{
 			return err
 		}
 
-		dumper, err := dumper.NewDumper(ethrpc, addrs)
+		unlock, err := dumper.LockDatabase()
 		if err != nil {
 			return err
 		}
+		defer unlock()
 
-		err = dumper.Dump()
+		dumper, err := dumper.NewDumper(ethrpc, addrs, opts)
 		if err != nil {
 			return err
 		}
-		go dumper.SubscribeEvents(cctx)
-		go dumper.SubscribeEthPrice(cctx, apikey)
+		defer dumper.Close()
 
 		srv, err := server.NewServer(endPoint, dumper)
 		if err != nil {
@@ -113,6 +182,15 @@ var ServerRunCmd = &cli.Command{
 			}
 		}()
 
+		// the first dump catches up with the chain in the background while the server
+		// already answers, /status reports the lag until it is done
+		go dumper.SubscribeEvents(cctx)
+		go dumper.CheckRPCHealth(cctx)
+		if ctx.Duration("reconcileInterval") > 0 {
+			go dumper.ReconcileBalances(cctx, ctx.Duration("reconcileInterval"))
+		}
+		go dumper.SubscribeEthPrice(cctx, apikey)
+
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 		<-quit
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/Me-Nodeslist/database/server"
+)
+
+var StatusCmd = &cli.Command{
+	Name:  "status",
+	Usage: "print the status of a running node-delegation server",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "endpoint",
+			Aliases: []string{"e"},
+			Usage:   "input the endpoint of the server, e.g.(http://localhost:8082)",
+			Value:   "http://localhost:8082",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		endpoint := ctx.String("endpoint")
+		if !strings.Contains(endpoint, "://") {
+			endpoint = "http://" + endpoint
+		}
+
+		client := http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Get(strings.TrimSuffix(endpoint, "/") + "/status")
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("status %d: %s", resp.StatusCode, body)
+		}
+
+		var status server.Status
+		err = json.Unmarshal(body, &status)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%-21s%d\n", "indexed block:", status.IndexedBlock)
+		fmt.Printf("%-21s%d\n", "chain head:", status.ChainHead)
+		fmt.Printf("%-21s%d blocks, %s\n", "lag:", status.LagBlocks, time.Duration(status.LagSeconds)*time.Second)
+		fmt.Printf("%-21s%s\n", "last dump:", formatUnix(status.LastDump))
+		fmt.Printf("%-21s%d\n", "failed events:", status.FailedEvents)
+		fmt.Printf("%-21s%s\n", "eth price refreshed:", formatUnix(status.EthPriceRefreshed))
+		for i, e := range status.RPCEndpoints {
+			health := "healthy"
+			if !e.Healthy {
+				health = "unhealthy"
+			}
+			if e.InUse {
+				health += ", in use"
+			}
+			fmt.Printf("%-21s%s at block %d, %s\n", fmt.Sprintf("rpc %d:", i), e.URL, e.Height, health)
+		}
+		return nil
+	},
+}
+
+func formatUnix(t int64) string {
+	if t == 0 {
+		return "never"
+	}
+	return time.Unix(t, 0).Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"errors"
+	"math/big"
+
+	"gorm.io/gorm"
+)
+
+// DelMEMOBalance is the DelMEMO balance of a holder, kept up to date from the Transfer
+// events including mints and burns. Redeeming is the amount burned by its redeems
+// that are neither canceled nor claimed yet.
+// Balance and Redeeming are never negative and have no leading zeros, the dumper keeps an
+// amount that would become negative at zero, so the text of a balance sorts by length and value.
+// A database that indexed DelMEMO before this table existed is seeded with the balances read
+// from the contract when the dumper starts.
+type DelMEMOBalance struct {
+	gorm.Model
+	Address   string `gorm:"uniqueIndex"`
+	Balance   string
+	Redeeming string
+	BlockTag
+}
+
+func (b *DelMEMOBalance) CreateDelMEMOBalance(tx *gorm.DB) error {
+	err := tx.Create(b).Error
+	if err != nil {
+		return err
+	}
+	return saveCreateRecord[DelMEMOBalance](tx, b.BlockNumber, b.ID)
+}
+
+func (b *DelMEMOBalance) UpdateDelMEMOBalance(tx *gorm.DB) error {
+	err := saveUpdateRecords[DelMEMOBalance](tx, b.BlockNumber, "address = ?", b.Address)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&DelMEMOBalance{}).Where("address = ?", b.Address).Updates(map[string]interface{}{"balance": b.Balance, "redeeming": b.Redeeming, "block_number": b.BlockNumber, "block_hash": b.BlockHash, "timestamp": b.Timestamp}).Error
+}
+
+func GetDelMEMOBalance(db *gorm.DB, address string) (DelMEMOBalance, error) {
+	var info DelMEMOBalance
+	err := db.Model(&DelMEMOBalance{}).Where("address = ?", address).First(&info).Error
+	return info, err
+}
+
+// GetDelMEMOHolders returns the addresses holding DelMEMO, largest balance first.
+// Balances are non-negative decimal strings without leading zeros, so a longer one is larger.
+func GetDelMEMOHolders(offset int, limit int) ([]DelMEMOBalance, error) {
+	var infos []DelMEMOBalance
+	err := GlobalDataBase.Model(&DelMEMOBalance{}).Where("balance != ?", "0").Order("length(balance) desc, balance desc").Offset(offset).Limit(limit).Find(&infos).Error
+	return infos, err
+}
+
+func GetDelMEMOHolderAmount() (int64, error) {
+	var length int64
+	err := GlobalDataBase.Model(&DelMEMOBalance{}).Where("balance != ?", "0").Count(&length).Error
+	return length, err
+}
+
+// GetDelMEMOBalances returns every balance row in a stable order, for paging through all of them
+func GetDelMEMOBalances(offset int, limit int) ([]DelMEMOBalance, error) {
+	var infos []DelMEMOBalance
+	err := GlobalDataBase.Model(&DelMEMOBalance{}).Order("id").Offset(offset).Limit(limit).Find(&infos).Error
+	return infos, err
+}
+
+// CorrectDelMEMOBalance sets the balance of the holder to its on-chain balance at the block,
+// unless an event after the block has changed the balance in the meantime. The correction is
+// stamped with the block and recorded like an event, so a reorg of the block reverts it.
+func CorrectDelMEMOBalance(address string, balance *big.Int, tag BlockTag) (bool, error) {
+	if balance.Sign() < 0 {
+		return false, errors.New("negative DelMEMO balance " + balance.String() + " of " + address)
+	}
+	corrected := false
+	err := GlobalDataBase.Transaction(func(tx *gorm.DB) error {
+		err := saveUpdateRecords[DelMEMOBalance](tx, tag.BlockNumber, "address = ? AND block_number <= ?", address, tag.BlockNumber)
+		if err != nil {
+			return err
+		}
+		res := tx.Model(&DelMEMOBalance{}).Where("address = ? AND block_number <= ?", address, tag.BlockNumber).Updates(map[string]interface{}{"balance": balance.String(), "block_number": tag.BlockNumber, "block_hash": tag.BlockHash, "timestamp": tag.Timestamp})
+		corrected = res.RowsAffected > 0
+		return res.Error
+	})
+	return corrected, err
+}
+
+// GetDelMEMOAccounts returns every address that received DelMEMO or redeemed it
+func GetDelMEMOAccounts(db *gorm.DB) ([]string, error) {
+	var receivers, minters, initiators []string
+	err := db.Model(&DelMEMOTransferInfo{}).Distinct().Pluck("to", &receivers).Error
+	if err != nil {
+		return nil, err
+	}
+	err = db.Model(&DelMEMOMintInfo{}).Distinct().Pluck("receiver", &minters).Error
+	if err != nil {
+		return nil, err
+	}
+	err = db.Model(&RedeemInfo{}).Distinct().Pluck("initiator", &initiators).Error
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var accounts []string
+	for _, list := range [][]string{receivers, minters, initiators} {
+		for _, account := range list {
+			if !seen[account] {
+				seen[account] = true
+				accounts = append(accounts, account)
+			}
+		}
+	}
+	return accounts, nil
+}
+
+// GetOpenRedeemInfos returns the redeems that are neither canceled nor claimed
+func GetOpenRedeemInfos(db *gorm.DB) ([]RedeemInfo, error) {
+	var infos []RedeemInfo
+	err := db.Model(&RedeemInfo{}).Where("canceled = ? AND claimed = ?", false, false).Find(&infos).Error
+	return infos, err
+}
+
+// SeedDelMEMOBalances stores the balances of a ledger that is still empty. They are not
+// recorded for a revert, the reconciliation corrects them if their block is reorganized.
+func SeedDelMEMOBalances(infos []DelMEMOBalance) error {
+	return GlobalDataBase.CreateInBatches(&infos, 100).Error
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetDelMEMOAccounts(t *testing.T) {
+	newTestDatabase(t)
+	db := GlobalDataBase
+
+	transfer := DelMEMOTransferInfo{From: "0x0", To: "alice", Amount: "5"}
+	if err := transfer.CreateDelMEMOTransferInfo(db); err != nil {
+		t.Fatal(err)
+	}
+	mint := DelMEMOMintInfo{Depositer: "bob", Receiver: "alice", Amount: "5"}
+	if err := mint.CreateDelMEMOMintInfo(db); err != nil {
+		t.Fatal(err)
+	}
+	redeem := RedeemInfo{RedeemID: "1", Initiator: "carol", RedeemAmount: "2"}
+	if err := redeem.CreateRedeemInfo(db); err != nil {
+		t.Fatal(err)
+	}
+
+	accounts, err := GetDelMEMOAccounts(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"alice", "carol"}; !reflect.DeepEqual(accounts, want) {
+		t.Errorf("accounts are %v, want %v", accounts, want)
+	}
+
+	redeems, err := GetOpenRedeemInfos(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(redeems) != 1 || redeems[0].Initiator != "carol" {
+		t.Errorf("open redeems are %v, want the redeem of carol", redeems)
+	}
+}
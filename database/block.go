@@ -0,0 +1,219 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// BlockTag marks the block that last wrote a row, so rows derived from an
+// orphaned block can be found after a chain reorganization.
+// Timestamp is the time of that block.
+type BlockTag struct {
+	BlockNumber int64 `gorm:"index"`
+	BlockHash   string
+	Timestamp   int64 `gorm:"index"`
+}
+
+// BlockInfo is the hash of a block the dumper has processed
+type BlockInfo struct {
+	BlockNumber int64 `gorm:"primarykey"`
+	BlockHash   string
+	ParentHash  string
+}
+
+// RevertRecord keeps a row as it was before an event of BlockNumber changed it.
+// Before is empty when the row was created by that event.
+type RevertRecord struct {
+	ID          uint  `gorm:"primarykey"`
+	BlockNumber int64 `gorm:"index"`
+	Model       string
+	RowID       uint
+	Before      string
+}
+
+// models that can be restored from a RevertRecord
+var revertModels = map[string]func() interface{}{
+	"LicenseInfo":              func() interface{} { return &LicenseInfo{} },
+	"LicenseOwnerHistory":      func() interface{} { return &LicenseOwnerHistory{} },
+	"LicenseDelegationHistory": func() interface{} { return &LicenseDelegationHistory{} },
+	"DelMEMOTransferInfo":      func() interface{} { return &DelMEMOTransferInfo{} },
+	"DelMEMOMintInfo":          func() interface{} { return &DelMEMOMintInfo{} },
+	"DelMEMOBalance":           func() interface{} { return &DelMEMOBalance{} },
+	"DelMEMOSupply":            func() interface{} { return &DelMEMOSupply{} },
+	"RedeemInfo":               func() interface{} { return &RedeemInfo{} },
+	"RewardWithdrawInfo":       func() interface{} { return &RewardWithdrawInfo{} },
+	"NodeInfo":                 func() interface{} { return &NodeInfo{} },
+	"NodeDailyDelegation":      func() interface{} { return &NodeDailyDelegation{} },
+	"CommissionRateHistory":    func() interface{} { return &CommissionRateHistory{} },
+}
+
+func modelName[T any]() string {
+	return reflect.TypeOf(new(T)).Elem().Name()
+}
+
+// saveCreateRecord remembers that row id of T was created in blockNumber
+func saveCreateRecord[T any](tx *gorm.DB, blockNumber int64, id uint) error {
+	record := RevertRecord{
+		BlockNumber: blockNumber,
+		Model:       modelName[T](),
+		RowID:       id,
+	}
+	return tx.Create(&record).Error
+}
+
+// saveUpdateRecords snapshots every row of T matching the query before it is updated in blockNumber
+func saveUpdateRecords[T any](tx *gorm.DB, blockNumber int64, query interface{}, args ...interface{}) error {
+	var rows []T
+	err := tx.Model(new(T)).Where(query, args...).Find(&rows).Error
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		before, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		var key struct{ ID uint }
+		err = json.Unmarshal(before, &key)
+		if err != nil {
+			return err
+		}
+		record := RevertRecord{
+			BlockNumber: blockNumber,
+			Model:       modelName[T](),
+			RowID:       key.ID,
+			Before:      string(before),
+		}
+		err = tx.Create(&record).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *BlockInfo) CreateBlockInfo(tx *gorm.DB) error {
+	return tx.Save(b).Error
+}
+
+func GetLatestBlockInfo() (BlockInfo, error) {
+	var info BlockInfo
+	err := GlobalDataBase.Model(&BlockInfo{}).Order("block_number desc").First(&info).Error
+	return info, err
+}
+
+func GetOldestBlockInfo() (BlockInfo, error) {
+	var info BlockInfo
+	err := GlobalDataBase.Model(&BlockInfo{}).Order("block_number").First(&info).Error
+	return info, err
+}
+
+// GetRecentBlockInfos returns the latest processed blocks, newest first
+func GetRecentBlockInfos(limit int) ([]BlockInfo, error) {
+	var infos []BlockInfo
+	err := GlobalDataBase.Model(&BlockInfo{}).Order("block_number desc").Limit(limit).Find(&infos).Error
+	return infos, err
+}
+
+// RevertBlocks undoes every change made by blocks >= blockNumber, newest change first,
+// and moves the block number cursor back to blockNumber. The failed events of the blocks stay
+// queued, replaying the blocks retries them, unless the blocks are orphaned: then their failed
+// events are forgotten, their archived logs are marked as removed and their cached headers are dropped.
+func RevertBlocks(blockNumber int64, orphaned bool) error {
+	return GlobalDataBase.Transaction(func(tx *gorm.DB) error {
+		var records []RevertRecord
+		err := tx.Model(&RevertRecord{}).Where("block_number >= ?", blockNumber).Order("id desc").Find(&records).Error
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			newModel, ok := revertModels[record.Model]
+			if !ok {
+				return errors.New("unknown revert model " + record.Model)
+			}
+			row := newModel()
+			if record.Before == "" {
+				err = tx.Unscoped().Delete(row, record.RowID).Error
+			} else {
+				err = json.Unmarshal([]byte(record.Before), row)
+				if err == nil {
+					err = tx.Unscoped().Save(row).Error
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+		err = tx.Where("block_number >= ?", blockNumber).Delete(&RevertRecord{}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("block_number >= ?", blockNumber).Delete(&BlockInfo{}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("block_number >= ?", blockNumber).Delete(&ProcessedEvent{}).Error
+		if err != nil {
+			return err
+		}
+		if orphaned {
+			err = tx.Where("block_number >= ?", blockNumber).Delete(&FailedEvent{}).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Model(&EventLog{}).Where("block_number >= ?", blockNumber).Update("removed", true).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Where("block_number >= ?", blockNumber).Delete(&BlockHeader{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Save(&DABlockNumber{BlockNumberKey: blockNumberKey, BlockNumber: blockNumber}).Error
+	})
+}
+
+// TruncateDerivedTables deletes every row derived from events, together with the
+// reorg tracking, processed and failed events, and moves the block number cursor back to 0.
+// The raw event archive is kept.
+func TruncateDerivedTables() error {
+	return GlobalDataBase.Transaction(func(tx *gorm.DB) error {
+		all := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped()
+		for _, newModel := range revertModels {
+			err := all.Delete(newModel()).Error
+			if err != nil {
+				return err
+			}
+		}
+		for _, model := range []interface{}{&RevertRecord{}, &BlockInfo{}, &ProcessedEvent{}, &FailedEvent{}} {
+			err := all.Delete(model).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Save(&DABlockNumber{BlockNumberKey: blockNumberKey, BlockNumber: 0}).Error
+	})
+}
+
+// PruneBlocks forgets revert records and block hashes below blockNumber, they can no longer be reorganized.
+// The blocks from the oldest unresolved failed event on are kept, the event is retried by rolling back to it.
+// The processed event marks are kept, so a log replayed from behind the cursor is never applied twice,
+// they are only deleted together with the rows of the blocks that are rolled back or reindexed.
+func PruneBlocks(tx *gorm.DB, blockNumber int64) error {
+	failedBlock, err := GetOldestUnresolvedFailedBlock(tx)
+	if err != nil {
+		return err
+	}
+	if failedBlock >= 0 {
+		blockNumber = min(blockNumber, failedBlock)
+	}
+	err = tx.Where("block_number < ?", blockNumber).Delete(&RevertRecord{}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Where("block_number < ?", blockNumber).Delete(&BlockInfo{}).Error
+}
@@ -0,0 +1,171 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+)
+
+func newTestDatabase(t *testing.T) {
+	t.Helper()
+	err := InitDatabase(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sqlDB, err := GlobalDataBase.DB()
+		if err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+func TestRevertBlocks(t *testing.T) {
+	newTestDatabase(t)
+	db := GlobalDataBase
+
+	// block 10 mints license 1, block 12 transfers it and mints license 2
+	minted := LicenseInfo{TokenID: "1", Owner: "alice", BlockTag: BlockTag{BlockNumber: 10, BlockHash: "0x10"}}
+	if err := minted.CreateLicenseInfo(db); err != nil {
+		t.Fatal(err)
+	}
+	transferred := LicenseInfo{TokenID: "1", Owner: "bob", BlockTag: BlockTag{BlockNumber: 12, BlockHash: "0x12"}}
+	if err := transferred.UpdateLicenseOwner(db); err != nil {
+		t.Fatal(err)
+	}
+	second := LicenseInfo{TokenID: "2", Owner: "carol", BlockTag: BlockTag{BlockNumber: 12, BlockHash: "0x12"}}
+	if err := second.CreateLicenseInfo(db); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int64{10, 12} {
+		info := BlockInfo{BlockNumber: n}
+		if err := info.CreateBlockInfo(db); err != nil {
+			t.Fatal(err)
+		}
+		event := ProcessedEvent{TxHash: fmt.Sprintf("0x%x", n), BlockNumber: n}
+		if err := event.CreateProcessedEvent(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := RevertBlocks(11, true); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := GetLicenseInfoByTokenID(db, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Owner != "alice" || info.BlockNumber != 10 || info.BlockHash != "0x10" {
+		t.Errorf("license 1 = %s at block %d %s, want alice at block 10 0x10", info.Owner, info.BlockNumber, info.BlockHash)
+	}
+	if _, err := GetLicenseInfoByTokenID(db, "2"); err == nil {
+		t.Error("license 2 minted in an orphaned block should be gone")
+	}
+
+	latest, err := GetLatestBlockInfo()
+	if err != nil || latest.BlockNumber != 10 {
+		t.Errorf("latest block = %d, %v, want 10", latest.BlockNumber, err)
+	}
+	var records, processed int64
+	db.Model(&RevertRecord{}).Where("block_number >= ?", 11).Count(&records)
+	db.Model(&ProcessedEvent{}).Where("block_number >= ?", 11).Count(&processed)
+	if records != 0 || processed != 0 {
+		t.Errorf("%d revert records and %d processed events left after block 11, want none", records, processed)
+	}
+
+	// a second revert of the same blocks changes nothing
+	if err := RevertBlocks(11, true); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := GetLicenseInfoByTokenID(db, "1"); info.Owner != "alice" {
+		t.Errorf("license 1 owner = %s after a second revert, want alice", info.Owner)
+	}
+
+	// reverting the mint removes the license as well
+	if err := RevertBlocks(10, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetLicenseInfoByTokenID(db, "1"); err == nil {
+		t.Error("license 1 should be gone after reverting its mint")
+	}
+}
+
+func TestPruneBlocks(t *testing.T) {
+	newTestDatabase(t)
+	db := GlobalDataBase
+
+	for _, n := range []int64{5, 20} {
+		info := LicenseInfo{TokenID: fmt.Sprint(n), BlockTag: BlockTag{BlockNumber: n}}
+		if err := info.CreateLicenseInfo(db); err != nil {
+			t.Fatal(err)
+		}
+		block := BlockInfo{BlockNumber: n}
+		if err := block.CreateBlockInfo(db); err != nil {
+			t.Fatal(err)
+		}
+		event := ProcessedEvent{TxHash: fmt.Sprintf("0x%x", n), BlockNumber: n}
+		if err := event.CreateProcessedEvent(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := PruneBlocks(db, 10); err != nil {
+		t.Fatal(err)
+	}
+	for _, model := range []interface{}{&RevertRecord{}, &BlockInfo{}} {
+		var below, above int64
+		db.Model(model).Where("block_number < ?", 10).Count(&below)
+		db.Model(model).Where("block_number >= ?", 10).Count(&above)
+		if below != 0 || above != 1 {
+			t.Errorf("%T: %d rows below and %d above block 10, want 0 and 1", model, below, above)
+		}
+	}
+
+	var processed int64
+	db.Model(&ProcessedEvent{}).Count(&processed)
+	if processed != 2 {
+		t.Errorf("%d processed events after pruning, want 2", processed)
+	}
+}
+
+func TestFailedEventKeepsBlocks(t *testing.T) {
+	newTestDatabase(t)
+	db := GlobalDataBase
+
+	for _, n := range []int64{5, 20} {
+		block := BlockInfo{BlockNumber: n}
+		if err := block.CreateBlockInfo(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	failed := FailedEvent{TxHash: "0x5", BlockNumber: 5}
+	if err := failed.CreateFailedEvent(db); err != nil {
+		t.Fatal(err)
+	}
+
+	// the block of the unresolved event is kept for the rollback that retries it
+	if err := PruneBlocks(db, 10); err != nil {
+		t.Fatal(err)
+	}
+	oldest, err := GetOldestBlockInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oldest.BlockNumber != 5 {
+		t.Errorf("oldest block is %d after pruning, want 5", oldest.BlockNumber)
+	}
+
+	// a rollback for the retry keeps the event queued, a reorg forgets it
+	if err := RevertBlocks(5, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetFailedEvent(db, "0x5", 0); err != nil {
+		t.Errorf("failed event after a rollback: %s", err)
+	}
+	if err := RevertBlocks(5, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetFailedEvent(db, "0x5", 0); err == nil {
+		t.Error("failed event of an orphaned block is still queued")
+	}
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProcessedEvent marks an event log that has been applied to the derived tables,
+// replaying the same log again is a no-op
+type ProcessedEvent struct {
+	TxHash      string `gorm:"primarykey"`
+	LogIndex    uint   `gorm:"primarykey;autoIncrement:false"`
+	BlockNumber int64  `gorm:"index"`
+}
+
+// EventLog is the raw log of a contract event together with its decoded arguments,
+// an append-only archive that derived tables can be rebuilt from. Logs of orphaned
+// blocks are kept but marked as removed.
+type EventLog struct {
+	ID          uint `gorm:"primarykey"`
+	CreatedAt   time.Time
+	Contract    string `gorm:"index"`
+	EventName   string `gorm:"index"`
+	BlockNumber int64  `gorm:"index"`
+	BlockHash   string `gorm:"uniqueIndex:idx_event_log"`
+	TxHash      string `gorm:"index"`
+	TxIndex     uint
+	LogIndex    uint `gorm:"uniqueIndex:idx_event_log"`
+	Timestamp   int64
+	Topics      string   // comma separated hex topics
+	Data        string   // hex encoded data
+	Args        string   // decoded arguments as json
+	Addresses   []string `gorm:"-"` // addresses among the arguments, stored as EventLogAddress
+	Removed     bool
+}
+
+// EventLogAddress links an archived log to an address among its arguments,
+// so the logs of an address are looked up by index
+type EventLogAddress struct {
+	EventLogID uint   `gorm:"primarykey;autoIncrement:false"`
+	Address    string `gorm:"primarykey;index"`
+}
+
+func (p *ProcessedEvent) CreateProcessedEvent(tx *gorm.DB) error {
+	return tx.Create(p).Error
+}
+
+func IsEventProcessed(db *gorm.DB, txHash string, logIndex uint) (bool, error) {
+	var length int64
+	err := db.Model(&ProcessedEvent{}).Where("tx_hash = ? AND log_index = ?", txHash, logIndex).Count(&length).Error
+	return length > 0, err
+}
+
+// ------------------EventLog--------------------
+var archiveStartKey = "archive_start_key"
+
+// SetArchiveStartBlock records the first block whose logs are all in the archive
+func SetArchiveStartBlock(blockNumber int64) error {
+	var daBlockNumber = DABlockNumber{
+		BlockNumberKey: archiveStartKey,
+		BlockNumber:    blockNumber,
+	}
+	return GlobalDataBase.Save(&daBlockNumber).Error
+}
+
+func GetArchiveStartBlock() (int64, error) {
+	var blockNumber DABlockNumber
+	err := GlobalDataBase.Model(&DABlockNumber{}).Where("`key` = ?", archiveStartKey).First(&blockNumber).Error
+	return blockNumber.BlockNumber, err
+}
+
+// CreateEventLog archives the log with its addresses, a log that is already archived is left as it is
+func (e *EventLog) CreateEventLog(tx *gorm.DB) error {
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(e)
+	if res.Error != nil || res.RowsAffected == 0 || len(e.Addresses) == 0 {
+		return res.Error
+	}
+	infos := make([]EventLogAddress, 0, len(e.Addresses))
+	for _, address := range e.Addresses {
+		infos = append(infos, EventLogAddress{EventLogID: e.ID, Address: address})
+	}
+	return tx.Create(&infos).Error
+}
+
+func GetEventLogsByTxHash(txHash string) ([]EventLog, error) {
+	var infos []EventLog
+	err := GlobalDataBase.Model(&EventLog{}).Where("tx_hash = ? AND removed = ?", txHash, false).Order("log_index").Find(&infos).Error
+	return infos, err
+}
+
+// GetEventLogsByAddress returns the events whose arguments contain the address, newest first
+func GetEventLogsByAddress(addr common.Address, offset int, limit int) ([]EventLog, error) {
+	var infos []EventLog
+	err := GlobalDataBase.Model(&EventLog{}).Joins("JOIN event_log_addresses ON event_log_addresses.event_log_id = event_logs.id").Where("event_log_addresses.address = ? AND event_logs.removed = ?", addr.Hex(), false).Order("event_logs.block_number desc, event_logs.log_index desc").Offset(offset).Limit(limit).Find(&infos).Error
+	return infos, err
+}
+
+// GetEventLogsInRange returns the archived logs from fromBlock to toBlock in chain order
+func GetEventLogsInRange(fromBlock int64, toBlock int64) ([]EventLog, error) {
+	var infos []EventLog
+	err := GlobalDataBase.Model(&EventLog{}).Where("block_number >= ? AND block_number <= ? AND removed = ?", fromBlock, toBlock, false).Order("block_number, tx_index, log_index").Find(&infos).Error
+	return infos, err
+}
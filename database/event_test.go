@@ -0,0 +1,45 @@
+package database
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestGetEventLogsByAddress(t *testing.T) {
+	newTestDatabase(t)
+	db := GlobalDataBase
+
+	alice := common.HexToAddress("0xa1")
+	bob := common.HexToAddress("0xb0")
+	logs := []EventLog{
+		{BlockNumber: 1, BlockHash: "0x1", Addresses: []string{alice.Hex(), bob.Hex()}},
+		{BlockNumber: 2, BlockHash: "0x2", Addresses: []string{bob.Hex()}},
+		{BlockNumber: 3, BlockHash: "0x3", Addresses: []string{alice.Hex()}, Removed: true},
+	}
+	for _, info := range logs {
+		if err := info.CreateEventLog(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// archiving a log again does not duplicate its addresses
+	again := EventLog{BlockNumber: 1, BlockHash: "0x1", Addresses: []string{alice.Hex()}}
+	if err := again.CreateEventLog(db); err != nil {
+		t.Fatal(err)
+	}
+
+	for addr, want := range map[common.Address][]int64{alice: {1}, bob: {2, 1}} {
+		infos, err := GetEventLogsByAddress(addr, 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int64
+		for _, info := range infos {
+			got = append(got, info.BlockNumber)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("logs of %s are in blocks %v, want %v", addr.Hex(), got, want)
+		}
+	}
+}
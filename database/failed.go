@@ -0,0 +1,98 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FailedEvent is an event whose handler failed, the dead-letter queue of the dumper.
+// Its changes were rolled back and the dumper retries it with a growing delay, by replaying
+// its block and all later ones, until the handler succeeds, then it is marked as resolved.
+type FailedEvent struct {
+	ID          uint `gorm:"primarykey"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Contract    string
+	EventName   string
+	BlockNumber int64  `gorm:"index"`
+	TxHash      string `gorm:"uniqueIndex:idx_failed_event"`
+	LogIndex    uint   `gorm:"uniqueIndex:idx_failed_event"`
+	Timestamp   int64
+	Log         string // the raw log as json
+	Error       string // error of the last attempt
+	Attempts    int
+	NextRetry   int64 `gorm:"index"` // unix time of the next attempt
+	Resolved    bool  `gorm:"index"`
+}
+
+// CreateFailedEvent puts the event into the queue, an event that is already queued is left as it is
+func (f *FailedEvent) CreateFailedEvent(tx *gorm.DB) error {
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(f).Error
+}
+
+// GetFailedEvent returns the queued event of the log
+func GetFailedEvent(tx *gorm.DB, txHash string, logIndex uint) (FailedEvent, error) {
+	var info FailedEvent
+	err := tx.Model(&FailedEvent{}).Where("tx_hash = ? AND log_index = ?", txHash, logIndex).First(&info).Error
+	return info, err
+}
+
+// GetDueFailedEvents returns the unresolved events whose next attempt is due at now, in chain order
+func GetDueFailedEvents(now int64) ([]FailedEvent, error) {
+	var infos []FailedEvent
+	err := GlobalDataBase.Model(&FailedEvent{}).Where("resolved = ? AND next_retry <= ?", false, now).Order("block_number, log_index").Find(&infos).Error
+	return infos, err
+}
+
+// GetFailedEvents returns the resolved or the unresolved events, newest first
+func GetFailedEvents(resolved bool, offset int, limit int) ([]FailedEvent, error) {
+	var infos []FailedEvent
+	err := GlobalDataBase.Model(&FailedEvent{}).Where("resolved = ?", resolved).Order("block_number desc, log_index desc").Offset(offset).Limit(limit).Find(&infos).Error
+	return infos, err
+}
+
+// UpdateFailedEventRetry records a failed attempt and when to try again
+func UpdateFailedEventRetry(tx *gorm.DB, id uint, errMsg string, attempts int, nextRetry int64) error {
+	return tx.Model(&FailedEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"error":      errMsg,
+		"attempts":   attempts,
+		"next_retry": nextRetry,
+		"resolved":   false,
+	}).Error
+}
+
+// ResolveFailedEvent marks the queued event of the log as resolved, it reports whether there was one
+func ResolveFailedEvent(tx *gorm.DB, txHash string, logIndex uint) (bool, error) {
+	res := tx.Model(&FailedEvent{}).Where("tx_hash = ? AND log_index = ? AND resolved = ?", txHash, logIndex, false).Update("resolved", true)
+	return res.RowsAffected > 0, res.Error
+}
+
+// RetryFailedEventNow makes the unresolved event due, so the next dump cycle retries it
+func RetryFailedEventNow(id uint) error {
+	res := GlobalDataBase.Model(&FailedEvent{}).Where("id = ? AND resolved = ?", id, false).Update("next_retry", 0)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetOldestUnresolvedFailedBlock returns the block of the oldest unresolved event, or -1 if there is none
+func GetOldestUnresolvedFailedBlock(tx *gorm.DB) (int64, error) {
+	var blockNumber *int64
+	err := tx.Model(&FailedEvent{}).Where("resolved = ?", false).Select("min(block_number)").Scan(&blockNumber).Error
+	if err != nil || blockNumber == nil {
+		return -1, err
+	}
+	return *blockNumber, nil
+}
+
+func GetUnresolvedFailedEventAmount() (int64, error) {
+	var amount int64
+	err := GlobalDataBase.Model(&FailedEvent{}).Where("resolved = ?", false).Count(&amount).Error
+	return amount, err
+}
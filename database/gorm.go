@@ -36,7 +36,8 @@ func InitDatabase(path string) error {
 		}
 	}
 
-	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "server.db")), &gorm.Config{})
+	// the dumper writes in transactions, let readers wait for them instead of failing
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "server.db")+"?_journal_mode=WAL&_busy_timeout=5000"), &gorm.Config{})
 	if err != nil {
 		logger.Error(err.Error())
 		return err
@@ -57,22 +58,33 @@ func InitDatabase(path string) error {
 		logger.Error(err.Error())
 		return err
 	}
-	db.AutoMigrate(&DABlockNumber{}, &LicenseInfo{}, &LicensePurchaseHistory{}, &DelMEMOMintInfo{}, &DelMEMOTransferInfo{}, &RedeemInfo{}, &RewardWithdrawInfo{}, &NodeInfo{}, &NodeDailyDelegation{})
+	err = registerMetrics(db)
+	if err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+
+	db.AutoMigrate(&DABlockNumber{}, &DumperLock{}, &BlockInfo{}, &BlockHeader{}, &RevertRecord{}, &ProcessedEvent{}, &FailedEvent{}, &EventLog{}, &EventLogAddress{}, &LicenseInfo{}, &LicenseOwnerHistory{}, &LicenseDelegationHistory{}, &LicensePurchaseHistory{}, &DelMEMOMintInfo{}, &DelMEMOTransferInfo{}, &DelMEMOBalance{}, &DelMEMOSupply{}, &RedeemInfo{}, &RewardWithdrawInfo{}, &NodeInfo{}, &NodeDailyDelegation{}, &CommissionRateHistory{})
+	err = seedDelMEMOSupply(db)
+	if err != nil {
+		logger.Error(err.Error())
+		return err
+	}
 	GlobalDataBase = db
 	return nil
 }
 
-func SetBlockNumber(blockNumber int64) error {
+func SetBlockNumber(tx *gorm.DB, blockNumber int64) error {
 	var daBlockNumber = DABlockNumber{
 		BlockNumberKey: blockNumberKey,
 		BlockNumber:    blockNumber,
 	}
-	return GlobalDataBase.Save(&daBlockNumber).Error
+	return tx.Save(&daBlockNumber).Error
 }
 
 func GetBlockNumber() (int64, error) {
 	var blockNumber DABlockNumber
-	err := GlobalDataBase.Model(&DABlockNumber{}).First(&blockNumber).Error
+	err := GlobalDataBase.Model(&DABlockNumber{}).Where("`key` = ?", blockNumberKey).First(&blockNumber).Error
 
 	return blockNumber.BlockNumber, err
 }
@@ -0,0 +1,28 @@
+package database
+
+import (
+	"gorm.io/gorm/clause"
+)
+
+// BlockHeader caches the header fields of a block the dumper needs, so its time
+// does not have to be fetched from the rpc again
+type BlockHeader struct {
+	BlockNumber int64 `gorm:"primarykey;autoIncrement:false"`
+	BlockHash   string
+	ParentHash  string
+	Timestamp   int64
+}
+
+func CreateBlockHeaders(headers []BlockHeader) error {
+	if len(headers) == 0 {
+		return nil
+	}
+	return GlobalDataBase.Clauses(clause.OnConflict{UpdateAll: true}).Create(&headers).Error
+}
+
+// GetBlockHeaders returns the cached headers of the block numbers, missing ones are left out
+func GetBlockHeaders(blockNumbers []int64) ([]BlockHeader, error) {
+	var headers []BlockHeader
+	err := GlobalDataBase.Model(&BlockHeader{}).Where("block_number IN ?", blockNumbers).Find(&headers).Error
+	return headers, err
+}
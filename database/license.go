@@ -14,6 +14,30 @@ type LicenseInfo struct {
 	TotalReward      string
 	InitialReward    string
 	WithdrawedReward string
+	Burned           bool // burned or redeemed, the owner is address(0)
+	BlockTag
+}
+
+// LicenseOwnerHistory is a transfer of a license, From is address(0) when it is minted
+// and To is address(0) when it is burned
+type LicenseOwnerHistory struct {
+	gorm.Model
+	TokenID string `gorm:"index;column:tokenid"`
+	From    string
+	To      string
+	TxHash  string
+	BlockTag
+}
+
+// LicenseDelegationHistory is a delegation change of a license, FromNode is address(0)
+// when it is delegated and ToNode is address(0) when it is undelegated
+type LicenseDelegationHistory struct {
+	gorm.Model
+	TokenID  string `gorm:"index;column:tokenid"`
+	FromNode string
+	ToNode   string
+	TxHash   string
+	BlockTag
 }
 
 type LicensePurchaseHistory struct {
@@ -29,25 +53,49 @@ func InitLicenseInfoTable() error {
 	return GlobalDataBase.AutoMigrate(&LicenseInfo{})
 }
 
-func (l *LicenseInfo) CreateLicenseInfo() error {
-	return GlobalDataBase.Create(l).Error
+func (l *LicenseInfo) CreateLicenseInfo(tx *gorm.DB) error {
+	err := tx.Create(l).Error
+	if err != nil {
+		return err
+	}
+	return saveCreateRecord[LicenseInfo](tx, l.BlockNumber, l.ID)
 }
 
-func (l *LicenseInfo) UpdateLicenseOwner() error {
-	return GlobalDataBase.Model(&LicenseInfo{}).Where("tokenid = ?", l.TokenID).Updates(map[string]interface{}{"owner": l.Owner}).Error
+func (l *LicenseInfo) UpdateLicenseOwner(tx *gorm.DB) error {
+	err := saveUpdateRecords[LicenseInfo](tx, l.BlockNumber, "tokenid = ?", l.TokenID)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&LicenseInfo{}).Where("tokenid = ?", l.TokenID).Updates(map[string]interface{}{"owner": l.Owner, "block_number": l.BlockNumber, "block_hash": l.BlockHash, "timestamp": l.Timestamp}).Error
 }
 
-func (l *LicenseInfo) UpdateLicenseDelegation() error {
-	return GlobalDataBase.Model(&LicenseInfo{}).Where("tokenid = ?", l.TokenID).Updates(map[string]interface{}{"delegated": l.Delegated, "delegated_node": l.DelegatedNode}).Error
+func (l *LicenseInfo) UpdateLicenseBurned(tx *gorm.DB) error {
+	err := saveUpdateRecords[LicenseInfo](tx, l.BlockNumber, "tokenid = ?", l.TokenID)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&LicenseInfo{}).Where("tokenid = ?", l.TokenID).Updates(map[string]interface{}{"owner": l.Owner, "burned": l.Burned, "block_number": l.BlockNumber, "block_hash": l.BlockHash, "timestamp": l.Timestamp}).Error
 }
 
-func (l *LicenseInfo) UpdateLicenseReward() error {
-	return GlobalDataBase.Model(&LicenseInfo{}).Where("tokenid = ?", l.TokenID).Updates(map[string]interface{}{"total_reward": l.TotalReward, "initial_reward": l.InitialReward, "withdrawed_reward": l.WithdrawedReward}).Error
+func (l *LicenseInfo) UpdateLicenseDelegation(tx *gorm.DB) error {
+	err := saveUpdateRecords[LicenseInfo](tx, l.BlockNumber, "tokenid = ?", l.TokenID)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&LicenseInfo{}).Where("tokenid = ?", l.TokenID).Updates(map[string]interface{}{"delegated": l.Delegated, "delegated_node": l.DelegatedNode, "block_number": l.BlockNumber, "block_hash": l.BlockHash, "timestamp": l.Timestamp}).Error
+}
+
+func (l *LicenseInfo) UpdateLicenseReward(tx *gorm.DB) error {
+	err := saveUpdateRecords[LicenseInfo](tx, l.BlockNumber, "tokenid = ?", l.TokenID)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&LicenseInfo{}).Where("tokenid = ?", l.TokenID).Updates(map[string]interface{}{"total_reward": l.TotalReward, "initial_reward": l.InitialReward, "withdrawed_reward": l.WithdrawedReward, "block_number": l.BlockNumber, "block_hash": l.BlockHash, "timestamp": l.Timestamp}).Error
 }
 
 func GetLicenseAmount() (int64, error) {
 	var length int64
-	err := GlobalDataBase.Model(&LicenseInfo{}).Count(&length).Error
+	err := GlobalDataBase.Model(&LicenseInfo{}).Where("burned = ?", false).Count(&length).Error
 	return length, err
 }
 
@@ -78,9 +126,9 @@ func GetLicenseAmountByNode(delegatedNodeAddr common.Address) (int64, error) {
 	return length, err
 }
 
-func GetLicenseInfoByTokenID(tokenID string) (LicenseInfo, error) {
+func GetLicenseInfoByTokenID(db *gorm.DB, tokenID string) (LicenseInfo, error) {
 	var licenseInfo LicenseInfo
-	err := GlobalDataBase.Model(&LicenseInfo{}).Where("tokenid = ?", tokenID).First(&licenseInfo).Error
+	err := db.Model(&LicenseInfo{}).Where("tokenid = ?", tokenID).First(&licenseInfo).Error
 	if err != nil {
 		return LicenseInfo{}, err
 	}
@@ -97,16 +145,57 @@ func GetLicenseInfosByOwner(ownerAddr common.Address, offset int, limit int) ([]
 	return licenseInfos, nil
 }
 
-func GetLicenseInfosByNode(delegatedNodeAddr common.Address, offset int, limit int) ([]LicenseInfo, error) {
+func GetLicenseInfosByNode(db *gorm.DB, delegatedNodeAddr common.Address, offset int, limit int) ([]LicenseInfo, error) {
 	var licenseInfos []LicenseInfo
 	delegatedNode := delegatedNodeAddr.Hex()
-	err := GlobalDataBase.Model(&LicenseInfo{}).Where("delegated_node = ?", delegatedNode).Offset(offset).Limit(limit).Find(&licenseInfos).Error
+	err := db.Model(&LicenseInfo{}).Where("delegated_node = ?", delegatedNode).Offset(offset).Limit(limit).Find(&licenseInfos).Error
 	if err != nil {
 		return licenseInfos, err
 	}
 	return licenseInfos, nil
 }
 
+// GetDelegatorAmountByNode returns how many owners have licenses delegated to the node
+func GetDelegatorAmountByNode(delegatedNodeAddr common.Address) (int64, error) {
+	var length int64
+	delegatedNode := delegatedNodeAddr.Hex()
+	err := GlobalDataBase.Model(&LicenseInfo{}).Where("delegated_node = ?", delegatedNode).Distinct("owner").Count(&length).Error
+	return length, err
+}
+
+// LicenseOwnerHistory
+
+func (h *LicenseOwnerHistory) CreateLicenseOwnerHistory(tx *gorm.DB) error {
+	err := tx.Create(h).Error
+	if err != nil {
+		return err
+	}
+	return saveCreateRecord[LicenseOwnerHistory](tx, h.BlockNumber, h.ID)
+}
+
+// GetLicenseOwnerHistories returns the transfers of the license, oldest first
+func GetLicenseOwnerHistories(tokenID string) ([]LicenseOwnerHistory, error) {
+	var histories []LicenseOwnerHistory
+	err := GlobalDataBase.Model(&LicenseOwnerHistory{}).Where("tokenid = ?", tokenID).Order("block_number, id").Find(&histories).Error
+	return histories, err
+}
+
+// LicenseDelegationHistory
+
+func (h *LicenseDelegationHistory) CreateLicenseDelegationHistory(tx *gorm.DB) error {
+	err := tx.Create(h).Error
+	if err != nil {
+		return err
+	}
+	return saveCreateRecord[LicenseDelegationHistory](tx, h.BlockNumber, h.ID)
+}
+
+// GetLicenseDelegationHistories returns the delegation changes of the license, oldest first
+func GetLicenseDelegationHistories(tokenID string, offset int, limit int) ([]LicenseDelegationHistory, error) {
+	var histories []LicenseDelegationHistory
+	err := GlobalDataBase.Model(&LicenseDelegationHistory{}).Where("tokenid = ?", tokenID).Order("block_number, id").Offset(offset).Limit(limit).Find(&histories).Error
+	return histories, err
+}
 
 // LicensePurchaseHistory
 
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+const DUMPER_LOCK_TIMEOUT = time.Minute // a lock not renewed for this long is left by a dead process
+
+var ErrDumperLocked = errors.New("the database is locked by another dumper")
+
+// DumperLock is the single row held by the process that writes the derived tables, so a
+// running server and a reindex never write at the same time. The holder renews it while
+// it runs, the lock of a process that died without releasing it expires.
+type DumperLock struct {
+	ID        uint `gorm:"primarykey"`
+	Owner     string
+	RenewedAt int64
+}
+
+const dumperLockID = 1
+
+// AcquireDumperLock takes the lock for owner, unless another owner has renewed it within the timeout
+func AcquireDumperLock(owner string, now int64) error {
+	lock := DumperLock{ID: dumperLockID, Owner: owner, RenewedAt: now}
+	res := GlobalDataBase.Clauses(clause.OnConflict{DoNothing: true}).Create(&lock)
+	if res.Error != nil || res.RowsAffected > 0 {
+		return res.Error
+	}
+
+	expired := now - int64(DUMPER_LOCK_TIMEOUT/time.Second)
+	res = GlobalDataBase.Model(&DumperLock{}).Where("id = ? AND (owner = ? OR renewed_at < ?)", dumperLockID, owner, expired).Updates(map[string]interface{}{"owner": owner, "renewed_at": now})
+	if res.Error != nil || res.RowsAffected > 0 {
+		return res.Error
+	}
+
+	var holder DumperLock
+	err := GlobalDataBase.Model(&DumperLock{}).Where("id = ?", dumperLockID).First(&holder).Error
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w %s, renewed at %s", ErrDumperLocked, holder.Owner, time.Unix(holder.RenewedAt, 0).Format(time.RFC3339))
+}
+
+// RenewDumperLock keeps the lock of owner from expiring, it fails if owner does not hold it anymore
+func RenewDumperLock(owner string, now int64) error {
+	res := GlobalDataBase.Model(&DumperLock{}).Where("id = ? AND owner = ?", dumperLockID, owner).Update("renewed_at", now)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errors.New("the dumper lock of " + owner + " was taken over")
+	}
+	return nil
+}
+
+func ReleaseDumperLock(owner string) error {
+	return GlobalDataBase.Where("id = ? AND owner = ?", dumperLockID, owner).Delete(&DumperLock{}).Error
+}
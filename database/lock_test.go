@@ -0,0 +1,35 @@
+package database
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDumperLock(t *testing.T) {
+	newTestDatabase(t)
+
+	if err := AcquireDumperLock("run", 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := AcquireDumperLock("reindex", 110); !errors.Is(err, ErrDumperLocked) {
+		t.Fatalf("second owner got %v, want the lock to be held", err)
+	}
+	if err := RenewDumperLock("run", 150); err != nil {
+		t.Fatal(err)
+	}
+
+	// the lock of a dead owner expires
+	if err := AcquireDumperLock("reindex", 150+60+1); err != nil {
+		t.Fatal(err)
+	}
+	if err := RenewDumperLock("run", 220); err == nil {
+		t.Error("the previous owner renewed a lock that was taken over")
+	}
+
+	if err := ReleaseDumperLock("reindex"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AcquireDumperLock("run", 230); err != nil {
+		t.Errorf("lock is still held after the release: %s", err)
+	}
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"time"
+
+	"github.com/Me-Nodeslist/database/metrics"
+	"gorm.io/gorm"
+)
+
+const METRICS_START_KEY = "metrics:start"
+
+type callbackRegister interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+// registerMetrics times every statement with gorm callbacks that run first and last
+// in the chain of each operation
+func registerMetrics(db *gorm.DB) error {
+	cb := db.Callback()
+	operations := []struct {
+		name   string
+		before callbackRegister
+		after  callbackRegister
+	}{
+		{"create", cb.Create().Before("*"), cb.Create().After("*")},
+		{"query", cb.Query().Before("*"), cb.Query().After("*")},
+		{"update", cb.Update().Before("*"), cb.Update().After("*")},
+		{"delete", cb.Delete().Before("*"), cb.Delete().After("*")},
+		{"row", cb.Row().Before("*"), cb.Row().After("*")},
+		{"raw", cb.Raw().Before("*"), cb.Raw().After("*")},
+	}
+	for _, op := range operations {
+		err := op.before.Register("metrics:before_"+op.name, startTimer)
+		if err != nil {
+			return err
+		}
+		err = op.after.Register("metrics:after_"+op.name, observeDuration(op.name))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startTimer(db *gorm.DB) {
+	db.InstanceSet(METRICS_START_KEY, time.Now())
+}
+
+func observeDuration(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		start, ok := db.InstanceGet(METRICS_START_KEY)
+		if !ok {
+			return
+		}
+		metrics.DBQueryDuration.WithLabelValues(operation, db.Statement.Table).Observe(time.Since(start.(time.Time)).Seconds())
+	}
+}
@@ -35,6 +35,17 @@ type NodeInfo struct {
 	OnlineDays                 int64
 	OnlineDays_RecentMonth     int64
 	OnlineDays_RecentWeek      int64
+	BlockTag
+}
+
+// CommissionRateHistory is a commission rate change of a node
+type CommissionRateHistory struct {
+	gorm.Model
+	NodeAddress string `gorm:"index"`
+	OldRate     uint8
+	NewRate     uint8
+	TxHash      string
+	BlockTag
 }
 
 type NodeDailyDelegation struct {
@@ -42,30 +53,51 @@ type NodeDailyDelegation struct {
 	NodeAddress      string
 	Date             uint16
 	DelegationAmount uint16
+	BlockTag
 }
 
 func InitNodeInfoTable() error {
 	return GlobalDataBase.AutoMigrate(&NodeInfo{})
 }
 
-func (n *NodeInfo) CreateNodeInfo() error {
-	return GlobalDataBase.Create(n).Error
+func (n *NodeInfo) CreateNodeInfo(tx *gorm.DB) error {
+	err := tx.Create(n).Error
+	if err != nil {
+		return err
+	}
+	return saveCreateRecord[NodeInfo](tx, n.BlockNumber, n.ID)
 }
 
-func (n *NodeInfo) UpdateNodeCommissionRate() error {
-	return GlobalDataBase.Model(&NodeInfo{}).Where("node_address = ?", n.NodeAddress).Updates(map[string]interface{}{"commission_rate": n.CommissionRate, "commission_rate_last_modify_at": n.CommissionRateLastModifyAt}).Error
+func (n *NodeInfo) UpdateNodeCommissionRate(tx *gorm.DB) error {
+	err := saveUpdateRecords[NodeInfo](tx, n.BlockNumber, "node_address = ?", n.NodeAddress)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&NodeInfo{}).Where("node_address = ?", n.NodeAddress).Updates(map[string]interface{}{"commission_rate": n.CommissionRate, "commission_rate_last_modify_at": n.CommissionRateLastModifyAt, "block_number": n.BlockNumber, "block_hash": n.BlockHash, "timestamp": n.Timestamp}).Error
 }
 
-func (n *NodeInfo) UpdateNodeDelegationAmount() error {
-	return GlobalDataBase.Model(&NodeInfo{}).Where("node_address = ?", n.NodeAddress).Updates(map[string]interface{}{"delegation_amount": n.DelegationAmount, "active": n.Active}).Error
+func (n *NodeInfo) UpdateNodeDelegationAmount(tx *gorm.DB) error {
+	err := saveUpdateRecords[NodeInfo](tx, n.BlockNumber, "node_address = ?", n.NodeAddress)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&NodeInfo{}).Where("node_address = ?", n.NodeAddress).Updates(map[string]interface{}{"delegation_amount": n.DelegationAmount, "active": n.Active, "block_number": n.BlockNumber, "block_hash": n.BlockHash, "timestamp": n.Timestamp}).Error
 }
 
-func (n *NodeInfo) UpdateNodeRewardInfo() error {
-	return GlobalDataBase.Model(&NodeInfo{}).Where("node_address = ?", n.NodeAddress).Updates(map[string]interface{}{"self_total_reward": n.SelfTotalReward, "self_withdrawed_reward": n.SelfWithdrawedReward, "delegation_reward": n.DelegationReward}).Error
+func (n *NodeInfo) UpdateNodeRewardInfo(tx *gorm.DB) error {
+	err := saveUpdateRecords[NodeInfo](tx, n.BlockNumber, "node_address = ?", n.NodeAddress)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&NodeInfo{}).Where("node_address = ?", n.NodeAddress).Updates(map[string]interface{}{"self_total_reward": n.SelfTotalReward, "self_withdrawed_reward": n.SelfWithdrawedReward, "delegation_reward": n.DelegationReward, "block_number": n.BlockNumber, "block_hash": n.BlockHash, "timestamp": n.Timestamp}).Error
 }
 
-func (n *NodeInfo) UpdateNodeOnlineDays() error {
-	return GlobalDataBase.Model(&NodeInfo{}).Where("node_address = ?", n.NodeAddress).Updates(map[string]interface{}{"online_days": n.OnlineDays, "online_days_recent_month": n.OnlineDays_RecentMonth, "online_days_recent_week": n.OnlineDays_RecentWeek}).Error
+func (n *NodeInfo) UpdateNodeOnlineDays(tx *gorm.DB) error {
+	err := saveUpdateRecords[NodeInfo](tx, n.BlockNumber, "node_address = ?", n.NodeAddress)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&NodeInfo{}).Where("node_address = ?", n.NodeAddress).Updates(map[string]interface{}{"online_days": n.OnlineDays, "online_days_recent_month": n.OnlineDays_RecentMonth, "online_days_recent_week": n.OnlineDays_RecentWeek, "block_number": n.BlockNumber, "block_hash": n.BlockHash, "timestamp": n.Timestamp}).Error
 }
 
 func GetNodeAmount() (int64, error) {
@@ -80,10 +112,10 @@ func GetActiveNodeAmount() (int64, error) {
 	return length, err
 }
 
-func GetNodeInfoByNodeAddress(nodeAddr common.Address) (NodeInfo, error) {
+func GetNodeInfoByNodeAddress(db *gorm.DB, nodeAddr common.Address) (NodeInfo, error) {
 	var nodeInfo NodeInfo
 	node := nodeAddr.Hex()
-	err := GlobalDataBase.Model(&NodeInfo{}).Where("node_address = ?", node).First(&nodeInfo).Error
+	err := db.Model(&NodeInfo{}).Where("node_address = ?", node).First(&nodeInfo).Error
 	if err != nil {
 		return NodeInfo{}, err
 	}
@@ -132,25 +164,47 @@ func InitNodeDailyDelegation() error {
 	return GlobalDataBase.AutoMigrate(&NodeDailyDelegation{})
 }
 
-func (n *NodeDailyDelegation) CreateNodeDailyDelegation() error {
-	return GlobalDataBase.Create(n).Error
+func (n *NodeDailyDelegation) CreateNodeDailyDelegation(tx *gorm.DB) error {
+	err := tx.Create(n).Error
+	if err != nil {
+		return err
+	}
+	return saveCreateRecord[NodeDailyDelegation](tx, n.BlockNumber, n.ID)
 }
 
-func (n *NodeDailyDelegation) UpdateNodeDailyDelegation() error {
-	return GlobalDataBase.Model(&NodeDailyDelegation{}).Where("node_address = ? AND date = ?", n.NodeAddress, n.Date).Updates(map[string]interface{}{"delegation_amount": n.DelegationAmount}).Error
+func (n *NodeDailyDelegation) UpdateNodeDailyDelegation(tx *gorm.DB) error {
+	err := saveUpdateRecords[NodeDailyDelegation](tx, n.BlockNumber, "node_address = ? AND date = ?", n.NodeAddress, n.Date)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&NodeDailyDelegation{}).Where("node_address = ? AND date = ?", n.NodeAddress, n.Date).Updates(map[string]interface{}{"delegation_amount": n.DelegationAmount, "block_number": n.BlockNumber, "block_hash": n.BlockHash, "timestamp": n.Timestamp}).Error
 }
 
-func GetNodeDailyDelegation(nodeAddr common.Address, date uint16) (NodeDailyDelegation, error) {
+func GetNodeDailyDelegation(db *gorm.DB, nodeAddr common.Address, date uint16) (NodeDailyDelegation, error) {
 	var nodeDailyDelegation NodeDailyDelegation
 	node := nodeAddr.Hex()
-	err := GlobalDataBase.Model(&NodeDailyDelegation{}).Where("node_address = ? AND date = ?", node, date).First(&nodeDailyDelegation).Error
+	err := db.Model(&NodeDailyDelegation{}).Where("node_address = ? AND date = ?", node, date).First(&nodeDailyDelegation).Error
 	if err != nil {
 		return NodeDailyDelegation{}, err
 	}
 	return nodeDailyDelegation, nil
 }
 
-func GetNodeRecentOnlineDays(nodeAddr common.Address, date uint16) (int64, int64, error) {
+// GetNodeDailyDelegations returns the delegation amounts of the node in its recent days, oldest first
+func GetNodeDailyDelegations(nodeAddr common.Address, days int) ([]NodeDailyDelegation, error) {
+	var infos []NodeDailyDelegation
+	node := nodeAddr.Hex()
+	err := GlobalDataBase.Model(&NodeDailyDelegation{}).Where("node_address = ?", node).Order("date desc").Limit(days).Find(&infos).Error
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(infos)-1; i < j; i, j = i+1, j-1 {
+		infos[i], infos[j] = infos[j], infos[i]
+	}
+	return infos, nil
+}
+
+func GetNodeRecentOnlineDays(db *gorm.DB, nodeAddr common.Address, date uint16) (int64, int64, error) {
 	var length_month int64
 	var length_week int64
 	node := nodeAddr.Hex()
@@ -162,11 +216,11 @@ func GetNodeRecentOnlineDays(nodeAddr common.Address, date uint16) (int64, int64
 	if date > 30 {
 		recentMonth = date - 30
 	}
-	err := GlobalDataBase.Model(&NodeDailyDelegation{}).Where("node_address = ? AND date >= ?", node, recentMonth).Count(&length_month).Error
+	err := db.Model(&NodeDailyDelegation{}).Where("node_address = ? AND date >= ?", node, recentMonth).Count(&length_month).Error
 	if err != nil {
 		return length_month, length_week, err
 	}
-	err = GlobalDataBase.Model(&NodeDailyDelegation{}).Where("node_address = ? AND date >= ?", node, recentWeek).Count(&length_week).Error
+	err = db.Model(&NodeDailyDelegation{}).Where("node_address = ? AND date >= ?", node, recentWeek).Count(&length_week).Error
 	if err != nil {
 		return length_month, length_week, err
 	}
@@ -181,3 +235,34 @@ func GetGlobalDailyDelegation(date uint16) (uint32, error) {
 	}
 	return globalDailyDelegation, nil
 }
+
+// ------------------CommissionRateHistory--------------------
+func (h *CommissionRateHistory) CreateCommissionRateHistory(tx *gorm.DB) error {
+	err := tx.Create(h).Error
+	if err != nil {
+		return err
+	}
+	return saveCreateRecord[CommissionRateHistory](tx, h.BlockNumber, h.ID)
+}
+
+// GetCommissionRateHistories returns the commission rate changes of the node, oldest first
+func GetCommissionRateHistories(nodeAddr common.Address, offset int, limit int) ([]CommissionRateHistory, error) {
+	var histories []CommissionRateHistory
+	node := nodeAddr.Hex()
+	err := GlobalDataBase.Model(&CommissionRateHistory{}).Where("node_address = ?", node).Order("block_number, id").Offset(offset).Limit(limit).Find(&histories).Error
+	return histories, err
+}
+
+// GetCommissionRateChangedNodes returns the addresses of the nodes that changed their commission rate since the timestamp
+func GetCommissionRateChangedNodes(since int64) (map[string]bool, error) {
+	var nodes []string
+	err := GlobalDataBase.Model(&CommissionRateHistory{}).Where("timestamp >= ?", since).Distinct().Pluck("node_address", &nodes).Error
+	if err != nil {
+		return nil, err
+	}
+	changed := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		changed[node] = true
+	}
+	return changed, nil
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"errors"
 	"math/big"
 	"time"
 
@@ -13,6 +14,7 @@ type DelMEMOTransferInfo struct {
 	From   string
 	To     string
 	Amount string
+	BlockTag
 }
 
 type DelMEMOMintInfo struct {
@@ -20,6 +22,7 @@ type DelMEMOMintInfo struct {
 	Depositer string
 	Receiver  string
 	Amount    string
+	BlockTag
 }
 
 type RedeemInfo struct {
@@ -32,20 +35,44 @@ type RedeemInfo struct {
 	UnlockDate   int64
 	Canceled     bool
 	Claimed      bool
+	BlockTag
 }
 
+// statuses of a redeem
+const (
+	REDEEM_LOCKED    = "locked"
+	REDEEM_CLAIMABLE = "claimable"
+	REDEEM_CLAIMED   = "claimed"
+	REDEEM_CANCELED  = "canceled"
+)
+
+// kinds of a reward withdrawal
+const (
+	WITHDRAW_REWARD     = "reward"     // RewardWithdraw event of the settlement contract
+	WITHDRAW_FOUNDATION = "foundation" // FoundationWithdraw event of the settlement contract
+)
+
+// RewardWithdrawInfo is a withdrawal from the settlement contract, Kind tells which event it came from.
+// Rows indexed before Kind and TxHash existed have them empty until the database is reindexed.
 type RewardWithdrawInfo struct {
 	gorm.Model
-	Receiver string
+	Receiver string `gorm:"index"`
+	Kind     string `gorm:"index"`
 	Amount   string
+	TxHash   string
+	BlockTag
 }
 
 func InitDelMEMOTransferInfoTable() error {
 	return GlobalDataBase.AutoMigrate(&DelMEMOTransferInfo{})
 }
 
-func (dm *DelMEMOTransferInfo) CreateDelMEMOTransferInfo() error {
-	return GlobalDataBase.Create(dm).Error
+func (dm *DelMEMOTransferInfo) CreateDelMEMOTransferInfo(tx *gorm.DB) error {
+	err := tx.Create(dm).Error
+	if err != nil {
+		return err
+	}
+	return saveCreateRecord[DelMEMOTransferInfo](tx, dm.BlockNumber, dm.ID)
 }
 
 // ------------------DelMEMOMintInfo--------------------
@@ -53,25 +80,12 @@ func InitDelMEMOMintInfoTable() error {
 	return GlobalDataBase.AutoMigrate(&DelMEMOMintInfo{})
 }
 
-func (dm *DelMEMOMintInfo) CreateDelMEMOMintInfo() error {
-	return GlobalDataBase.Create(dm).Error
-}
-
-func GetAllMintAmount() (*big.Int, error) {
-	var infos []DelMEMOMintInfo
-	res := big.NewInt(0)
-	err := GlobalDataBase.Model(&DelMEMOMintInfo{}).Find(&infos).Error
+func (dm *DelMEMOMintInfo) CreateDelMEMOMintInfo(tx *gorm.DB) error {
+	err := tx.Create(dm).Error
 	if err != nil {
-		return nil, err
-	}
-	for _, info := range infos {
-		amount, ok := new(big.Int).SetString(info.Amount, 10)
-		if !ok {
-			continue
-		}
-		res = res.Add(res, amount)
+		return err
 	}
-	return res, err
+	return saveCreateRecord[DelMEMOMintInfo](tx, dm.BlockNumber, dm.ID)
 }
 
 // ------------------RedeemInfo--------------------
@@ -79,18 +93,62 @@ func InitRedeemInfo() error {
 	return GlobalDataBase.AutoMigrate(&RedeemInfo{})
 }
 
-func (r *RedeemInfo) CreateRedeemInfo() error {
-	return GlobalDataBase.Create(r).Error
+func (r *RedeemInfo) CreateRedeemInfo(tx *gorm.DB) error {
+	err := tx.Create(r).Error
+	if err != nil {
+		return err
+	}
+	return saveCreateRecord[RedeemInfo](tx, r.BlockNumber, r.ID)
+}
+
+func (r *RedeemInfo) UpdateRedeemInfo(tx *gorm.DB) error {
+	err := saveUpdateRecords[RedeemInfo](tx, r.BlockNumber, "redeemid = ?", r.RedeemID)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&RedeemInfo{}).Where("redeemid = ?", r.RedeemID).Updates(map[string]interface{}{"canceled": r.Canceled, "claimed": r.Claimed, "block_number": r.BlockNumber, "block_hash": r.BlockHash, "timestamp": r.Timestamp}).Error
+}
+
+func GetRedeemInfo(db *gorm.DB, redeemID string) (RedeemInfo, error) {
+	var info RedeemInfo
+	err := db.Model(&RedeemInfo{}).Where("redeemid = ?", redeemID).First(&info).Error
+	return info, err
 }
 
-func (r *RedeemInfo) UpdateRedeemInfo() error {
-	return GlobalDataBase.Model(&RedeemInfo{}).Where("redeemid = ?", r.RedeemID).Updates(map[string]interface{}{"canceled": r.Canceled, "claimed": r.Claimed}).Error
+// Status tells whether the redeem is locked, claimable, claimed or canceled at now
+func (r *RedeemInfo) Status(now int64) string {
+	switch {
+	case r.Canceled:
+		return REDEEM_CANCELED
+	case r.Claimed:
+		return REDEEM_CLAIMED
+	case r.UnlockDate <= now:
+		return REDEEM_CLAIMABLE
+	default:
+		return REDEEM_LOCKED
+	}
 }
 
-func GetRedeemInfosByInitiator(initiatorAddr common.Address, offset int, limit int) ([]RedeemInfo, error) {
+// GetRedeemInfosByInitiator returns the redeems of the initiator newest first,
+// only the ones that are in status at now unless status is empty
+func GetRedeemInfosByInitiator(initiatorAddr common.Address, status string, now int64, offset int, limit int) ([]RedeemInfo, error) {
 	var infos []RedeemInfo
 	initiator := initiatorAddr.Hex()
-	err := GlobalDataBase.Model(&RedeemInfo{}).Where("initiator = ?", initiator).Offset(offset).Limit(limit).Find(&infos).Error
+	db := GlobalDataBase.Model(&RedeemInfo{}).Where("initiator = ?", initiator)
+	switch status {
+	case "":
+	case REDEEM_LOCKED:
+		db = db.Where("canceled = ? AND claimed = ? AND unlock_date > ?", false, false, now)
+	case REDEEM_CLAIMABLE:
+		db = db.Where("canceled = ? AND claimed = ? AND unlock_date <= ?", false, false, now)
+	case REDEEM_CLAIMED:
+		db = db.Where("canceled = ? AND claimed = ?", false, true)
+	case REDEEM_CANCELED:
+		db = db.Where("canceled = ?", true)
+	default:
+		return nil, errors.New("unknown redeem status " + status)
+	}
+	err := db.Order("block_number desc, id desc").Offset(offset).Limit(limit).Find(&infos).Error
 	if err != nil {
 		return infos, err
 	}
@@ -196,25 +254,41 @@ func InitRewardWithdrawInfoTable() error {
 	return GlobalDataBase.AutoMigrate(&RewardWithdrawInfo{})
 }
 
-func (rw *RewardWithdrawInfo) CreateRewardWithdrawInfo() error {
-	return GlobalDataBase.Create(rw).Error
+func (rw *RewardWithdrawInfo) CreateRewardWithdrawInfo(tx *gorm.DB) error {
+	err := tx.Create(rw).Error
+	if err != nil {
+		return err
+	}
+	return saveCreateRecord[RewardWithdrawInfo](tx, rw.BlockNumber, rw.ID)
 }
 
-func GetWithdrawInfosByReceiver(receiverAddr common.Address, offset int, limit int) ([]RewardWithdrawInfo, error) {
+// GetWithdrawInfosByReceiver returns the withdrawals of the receiver newest first,
+// only the ones of kind unless it is empty
+func GetWithdrawInfosByReceiver(receiverAddr common.Address, kind string, offset int, limit int) ([]RewardWithdrawInfo, error) {
 	var infos []RewardWithdrawInfo
 	receiver := receiverAddr.Hex()
-	err := GlobalDataBase.Model(&RewardWithdrawInfo{}).Where("receiver = ?", receiver).Offset(offset).Limit(limit).Find(&infos).Error
+	db := GlobalDataBase.Model(&RewardWithdrawInfo{}).Where("receiver = ?", receiver)
+	if kind != "" {
+		db = db.Where("kind = ?", kind)
+	}
+	err := db.Order("block_number desc, id desc").Offset(offset).Limit(limit).Find(&infos).Error
 	if err != nil {
 		return nil, err
 	}
 	return infos, err
 }
 
-func GetTotalWithdrawAmountByReceiver(receiverAddr common.Address) (*big.Int, error) {
+// GetTotalWithdrawAmountByReceiver returns what the receiver withdrew in withdrawals of kind,
+// or in all of them if kind is empty
+func GetTotalWithdrawAmountByReceiver(receiverAddr common.Address, kind string) (*big.Int, error) {
 	var infos []RewardWithdrawInfo
 	res := big.NewInt(0)
 	receiver := receiverAddr.Hex()
-	err := GlobalDataBase.Model(&RewardWithdrawInfo{}).Where("receiver = ?", receiver).Find(&infos).Error
+	db := GlobalDataBase.Model(&RewardWithdrawInfo{}).Where("receiver = ?", receiver)
+	if kind != "" {
+		db = db.Where("kind = ?", kind)
+	}
+	err := db.Find(&infos).Error
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,24 @@
+package database
+
+import "testing"
+
+func TestRedeemInfoStatus(t *testing.T) {
+	const now = 1000
+	tests := []struct {
+		name string
+		info RedeemInfo
+		want string
+	}{
+		{"locked", RedeemInfo{UnlockDate: now + 1}, REDEEM_LOCKED},
+		{"claimable at unlock", RedeemInfo{UnlockDate: now}, REDEEM_CLAIMABLE},
+		{"claimable after unlock", RedeemInfo{UnlockDate: now - 1}, REDEEM_CLAIMABLE},
+		{"claimed", RedeemInfo{UnlockDate: now - 1, Claimed: true}, REDEEM_CLAIMED},
+		{"canceled while locked", RedeemInfo{UnlockDate: now + 1, Canceled: true}, REDEEM_CANCELED},
+		{"canceled wins over claimed", RedeemInfo{UnlockDate: now - 1, Claimed: true, Canceled: true}, REDEEM_CANCELED},
+	}
+	for _, tt := range tests {
+		if got := tt.info.Status(now); got != tt.want {
+			t.Errorf("%s: Status() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
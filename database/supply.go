@@ -0,0 +1,128 @@
+package database
+
+import (
+	"errors"
+	"math/big"
+
+	"gorm.io/gorm"
+)
+
+// DelMEMOSupply is the single row of DelMEMO totals the dumper keeps up to date event by
+// event, so they never have to be summed up from the event tables.
+// Minted and Claimed are MEMO deposited by mints and paid out by claims, Redeemed and
+// Canceled are DelMEMO of all redeems and of the canceled ones, Circulating is the DelMEMO
+// supply after mints and burns, and ServiceFees is the DelMEMO of claimed redeems that
+// was not paid out as MEMO.
+type DelMEMOSupply struct {
+	gorm.Model
+	Minted          string
+	Redeemed        string
+	Claimed         string
+	Canceled        string
+	CanceledRedeems int64
+	Circulating     string
+	ServiceFees     string
+	BlockTag
+}
+
+func (s *DelMEMOSupply) CreateDelMEMOSupply(tx *gorm.DB) error {
+	err := tx.Create(s).Error
+	if err != nil {
+		return err
+	}
+	return saveCreateRecord[DelMEMOSupply](tx, s.BlockNumber, s.ID)
+}
+
+func (s *DelMEMOSupply) UpdateDelMEMOSupply(tx *gorm.DB) error {
+	err := saveUpdateRecords[DelMEMOSupply](tx, s.BlockNumber, "id = ?", s.ID)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&DelMEMOSupply{}).Where("id = ?", s.ID).Updates(map[string]interface{}{"minted": s.Minted, "redeemed": s.Redeemed, "claimed": s.Claimed, "canceled": s.Canceled, "canceled_redeems": s.CanceledRedeems, "circulating": s.Circulating, "service_fees": s.ServiceFees, "block_number": s.BlockNumber, "block_hash": s.BlockHash, "timestamp": s.Timestamp}).Error
+}
+
+// GetDelMEMOSupply returns the totals, all zero before the first DelMEMO event
+func GetDelMEMOSupply(db *gorm.DB) (DelMEMOSupply, error) {
+	var info DelMEMOSupply
+	err := db.Model(&DelMEMOSupply{}).First(&info).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return DelMEMOSupply{
+			Minted:      "0",
+			Redeemed:    "0",
+			Claimed:     "0",
+			Canceled:    "0",
+			Circulating: "0",
+			ServiceFees: "0",
+		}, nil
+	}
+	return info, err
+}
+
+// seedDelMEMOSupply fills the totals from the mint and redeem tables of a database that indexed
+// DelMEMO before the totals were kept, so they do not start at zero after an upgrade.
+// The event tables do not record the DelMEMO minted by mints and cancels, so Circulating is
+// estimated as minted - redeemed + canceled, DelMEMO being minted 1:1 for the deposited MEMO.
+// Claimed and ServiceFees use the claim amount of the redeems. Reindex for exact totals.
+func seedDelMEMOSupply(db *gorm.DB) error {
+	var length int64
+	err := db.Model(&DelMEMOSupply{}).Count(&length).Error
+	if err != nil || length > 0 {
+		return err
+	}
+
+	var mints []DelMEMOMintInfo
+	err = db.Model(&DelMEMOMintInfo{}).Find(&mints).Error
+	if err != nil {
+		return err
+	}
+	var redeems []RedeemInfo
+	err = db.Model(&RedeemInfo{}).Find(&redeems).Error
+	if err != nil {
+		return err
+	}
+	if len(mints) == 0 && len(redeems) == 0 {
+		return nil
+	}
+
+	minted, redeemed, claimed, canceled, serviceFees := big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0)
+	blockNumber := int64(0)
+	for _, mint := range mints {
+		amount, ok := new(big.Int).SetString(mint.Amount, 10)
+		if !ok {
+			return errors.New("invalid mint amount " + mint.Amount)
+		}
+		minted.Add(minted, amount)
+		blockNumber = max(blockNumber, mint.BlockNumber)
+	}
+	info := DelMEMOSupply{}
+	for _, redeem := range redeems {
+		amount, ok := new(big.Int).SetString(redeem.RedeemAmount, 10)
+		if !ok {
+			return errors.New("invalid amount " + redeem.RedeemAmount + " of redeem " + redeem.RedeemID)
+		}
+		redeemed.Add(redeemed, amount)
+		blockNumber = max(blockNumber, redeem.BlockNumber)
+		switch {
+		case redeem.Canceled:
+			canceled.Add(canceled, amount)
+			info.CanceledRedeems++
+		case redeem.Claimed:
+			claimAmount, ok := new(big.Int).SetString(redeem.ClaimAmount, 10)
+			if !ok {
+				return errors.New("invalid claim amount " + redeem.ClaimAmount + " of redeem " + redeem.RedeemID)
+			}
+			claimed.Add(claimed, claimAmount)
+			serviceFees.Add(serviceFees, new(big.Int).Sub(amount, claimAmount))
+		}
+	}
+
+	info.Minted = minted.String()
+	info.Redeemed = redeemed.String()
+	info.Claimed = claimed.String()
+	info.Canceled = canceled.String()
+	info.Circulating = new(big.Int).Add(new(big.Int).Sub(minted, redeemed), canceled).String()
+	info.ServiceFees = serviceFees.String()
+	info.BlockNumber = blockNumber
+	logger.Warnf("seeded the DelMEMO totals from %d mints and %d redeems, the circulating supply is estimated until a reindex", len(mints), len(redeems))
+	return db.Create(&info).Error
+}
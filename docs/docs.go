@@ -15,9 +15,14 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/license/amount": {
+        "/admin/event/failed": {
             "get": {
-                "description": "Get all license amount that have been sold, and all license amount that have been delegated",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Query the dead-letter queue of the dumper, newest first, support paging. Unresolved events are retried with a growing delay.",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,16 +30,51 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "License"
+                    "Admin"
+                ],
+                "summary": "Get the events whose handlers failed",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "list the events that succeeded on a retry instead of the unresolved ones (default false)",
+                        "name": "resolved",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "paging start index (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of items to return per page(default 10)",
+                        "name": "limit",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get all license amount and delegated license amount",
                 "responses": {
                     "200": {
-                        "description": "return the amount",
+                        "description": "return failed event list successfully",
+                        "schema": {
+                            "$ref": "#/definitions/server.FailedEvents"
+                        }
+                    },
+                    "400": {
+                        "description": "request parameter error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
-                                "type": "integer"
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "missing or invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -50,9 +90,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/license/amount/owner/{address}": {
-            "get": {
-                "description": "Get the license amount that the wallet address has purchased",
+        "/admin/event/failed/{id}/retry": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Make an unresolved event of the dead-letter queue due, the next dump cycle rolls back to its block and replays the event together with the events of all later blocks in chain order.",
                 "consumes": [
                     "application/json"
                 ],
@@ -60,25 +105,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "License"
+                    "Admin"
                 ],
-                "summary": "Get all license amount of the owner",
+                "summary": "Retry a failed event",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "owner address(an ethereum address with prefix '0x')",
-                        "name": "address",
+                        "type": "integer",
+                        "description": "id of the failed event",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "return amount",
+                        "description": "the event will be retried in the next dump cycle",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
-                                "type": "integer"
+                                "type": "boolean"
                             }
                         }
                     },
@@ -91,6 +136,24 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "401": {
+                        "description": "missing or invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "no unresolved event with the id",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "internal server error",
                         "schema": {
@@ -103,9 +166,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/license/info/owner/{address}": {
+        "/chain/height": {
             "get": {
-                "description": "Query the license information owned by the owner through the wallet address, support paging",
+                "description": "Query the last block dumped into the database and the latest block of the chain, so clients know how fresh the data is",
                 "consumes": [
                     "application/json"
                 ],
@@ -113,17 +176,33 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "License"
+                    "Chain"
                 ],
-                "summary": "Get the license information of the specified owner in pages",
+                "summary": "Get the indexed block height and the chain head",
+                "responses": {
+                    "200": {
+                        "description": "return the heights successfully",
+                        "schema": {
+                            "$ref": "#/definitions/server.ChainHeight"
+                        }
+                    }
+                }
+            }
+        },
+        "/delmemo/holders": {
+            "get": {
+                "description": "Query the addresses holding DelMEMO, largest balance first, support paging. Redeeming is the amount burned by redeems that are neither canceled nor claimed. Amount is the number of all holders.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DelMEMO"
+                ],
+                "summary": "Get the DelMEMO holders",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "owner address(an ethereum address with prefix '0x')",
-                        "name": "address",
-                        "in": "path",
-                        "required": true
-                    },
                     {
                         "type": "integer",
                         "description": "paging start index (default 0)",
@@ -139,9 +218,9 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "return license info list successfully",
+                        "description": "return holder list successfully",
                         "schema": {
-                            "$ref": "#/definitions/server.LicenseInfos"
+                            "$ref": "#/definitions/server.DelMEMOHolders"
                         }
                     },
                     "400": {
@@ -165,9 +244,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/license/price": {
+        "/delmemo/supply": {
             "get": {
-                "description": "Get license price, include how many USDT and how many ETH",
+                "description": "Query the DelMEMO totals: MEMO deposited by mints, DelMEMO redeemed, MEMO claimed, DelMEMO of canceled redeems and their number, the circulating DelMEMO, and the service fees kept from claimed redeems",
                 "consumes": [
                     "application/json"
                 ],
@@ -175,14 +254,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "License"
+                    "DelMEMO"
                 ],
-                "summary": "Get license price",
+                "summary": "Get the DelMEMO supply",
                 "responses": {
                     "200": {
-                        "description": "return the price",
+                        "description": "return the totals successfully",
                         "schema": {
-                            "$ref": "#/definitions/server.LicensePrice"
+                            "$ref": "#/definitions/server.DelMEMOSupply"
                         }
                     },
                     "500": {
@@ -197,9 +276,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/license/purchase": {
-            "post": {
-                "description": "User pay for license, and the server will check the payment, if valid, server will mint license for the user",
+        "/event/address/{address}": {
+            "get": {
+                "description": "Query the archived contract events whose arguments contain the address, newest first, support paging",
                 "consumes": [
                     "application/json"
                 ],
@@ -207,26 +286,35 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "License"
+                    "Event"
                 ],
-                "summary": "Handle license purchase",
+                "summary": "Get the on-chain events related to an address",
                 "parameters": [
                     {
-                        "description": "receiver: the buyer; amount: buy how many licenses; value: pay how many wei; txhash: the transaction hash that receiver transfer eth to admin",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/server.MintRequest"
-                        }
+                        "type": "string",
+                        "description": "an ethereum address with prefix '0x'",
+                        "name": "address",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "paging start index (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of items to return per page(default 10)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "return the transaction hash of mintLicense",
+                        "description": "return event list successfully",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/server.EventLogs"
                         }
                     },
                     "400": {
@@ -250,9 +338,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/node/amount": {
+        "/event/tx/{txHash}": {
             "get": {
-                "description": "Query the amount of the registered nodes in nodelist server",
+                "description": "Query the archived contract events emitted by the transaction",
                 "consumes": [
                     "application/json"
                 ],
@@ -260,17 +348,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Node"
+                    "Event"
+                ],
+                "summary": "Get the on-chain events of a transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "transaction hash with prefix '0x'",
+                        "name": "txHash",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get the amount of all registered nodes",
                 "responses": {
                     "200": {
-                        "description": "return the nodes amount successfully",
+                        "description": "return event list successfully",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "integer"
-                            }
+                            "$ref": "#/definitions/server.EventLogs"
                         }
                     },
                     "500": {
@@ -285,9 +379,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/node/info": {
+        "/license/amount": {
             "get": {
-                "description": "Query the information of all nodes, support paging",
+                "description": "Get all license amount that have been sold, and all license amount that have been delegated",
                 "consumes": [
                     "application/json"
                 ],
@@ -295,36 +389,16 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Node"
-                ],
-                "summary": "Get all nodes information(paging)",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "paging start index (default 0)",
-                        "name": "offset",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "number of items to return per page(default 10)",
-                        "name": "limit",
-                        "in": "query"
-                    }
+                    "License"
                 ],
+                "summary": "Get all license amount and delegated license amount",
                 "responses": {
                     "200": {
-                        "description": "return node info list successfully",
-                        "schema": {
-                            "$ref": "#/definitions/server.NodeInfos"
-                        }
-                    },
-                    "400": {
-                        "description": "request parameter error",
+                        "description": "return the amount",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
-                                "type": "string"
+                                "type": "integer"
                             }
                         }
                     },
@@ -340,9 +414,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/node/info/delegation/{address}": {
+        "/license/amount/owner/{address}": {
             "get": {
-                "description": "Query the information of the nodes that the specific owner has delegated his licenses to",
+                "description": "Get the license amount that the wallet address has purchased",
                 "consumes": [
                     "application/json"
                 ],
@@ -350,9 +424,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Node"
+                    "License"
                 ],
-                "summary": "Get the nodes information delegated by a specific owner",
+                "summary": "Get all license amount of the owner",
                 "parameters": [
                     {
                         "type": "string",
@@ -364,9 +438,12 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "return delegated nodes information successfully",
+                        "description": "return amount and delegated amount",
                         "schema": {
-                            "$ref": "#/definitions/server.NodeInfos"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
                         }
                     },
                     "400": {
@@ -390,9 +467,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/node/info/owner/{address}": {
+        "/license/history/{tokenID}": {
             "get": {
-                "description": "Query the node information through the owner address who owned the node",
+                "description": "Query every delegation change of the license, oldest first. 'fromNode' is address(0) when delegated and 'toNode' is address(0) when undelegated, support paging",
                 "consumes": [
                     "application/json"
                 ],
@@ -400,23 +477,44 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Node"
+                    "License"
                 ],
-                "summary": "Get the node information of the owner",
+                "summary": "Get the delegation history of a license in pages",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "owner address(an ethereum address with prefix '0x')",
-                        "name": "address",
+                        "description": "license token id",
+                        "name": "tokenID",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "paging start index (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of items to return per page(default 10)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "return the node information successfully",
+                        "description": "return delegation history successfully",
                         "schema": {
-                            "$ref": "#/definitions/server.NodeInfo"
+                            "$ref": "#/definitions/server.LicenseDelegationHistories"
+                        }
+                    },
+                    "400": {
+                        "description": "request parameter error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
@@ -431,9 +529,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/node/info/recipient/{address}": {
+        "/license/info/owner/{address}": {
             "get": {
-                "description": "Query the nodes information list through the recipient address who receives the node reward",
+                "description": "Query the license information owned by the owner through the wallet address, support paging",
                 "consumes": [
                     "application/json"
                 ],
@@ -441,13 +539,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Node"
+                    "License"
                 ],
-                "summary": "Get the nodes information by the recipient",
+                "summary": "Get the license information of the specified owner in pages",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "recipient address(an ethereum address with prefix '0x')",
+                        "description": "owner address(an ethereum address with prefix '0x')",
                         "name": "address",
                         "in": "path",
                         "required": true
@@ -467,9 +565,18 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "return the nodes information list successfully",
+                        "description": "return license info list successfully",
                         "schema": {
-                            "$ref": "#/definitions/server.NodeInfos"
+                            "$ref": "#/definitions/server.LicenseInfos"
+                        }
+                    },
+                    "400": {
+                        "description": "request parameter error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
@@ -484,9 +591,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/reward/info/{address}": {
+        "/license/owner/history/{tokenID}": {
             "get": {
-                "description": "Query all license rewards and node reward information of the specific owner, include total and withdrawed",
+                "description": "Query every transfer of the license from mint to burn, oldest first. 'from' is address(0) when minted and 'to' is address(0) when burned",
                 "consumes": [
                     "application/json"
                 ],
@@ -494,13 +601,642 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Reward"
+                    "License"
                 ],
-                "summary": "Get the account's reward information",
+                "summary": "Get the ownership history of a license",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "owner address(an ethereum address with prefix '0x')",
+                        "description": "license token id",
+                        "name": "tokenID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "return ownership history successfully",
+                        "schema": {
+                            "$ref": "#/definitions/server.LicenseOwnerHistories"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/license/price": {
+            "get": {
+                "description": "Get license price, include how many USDT and how many ETH",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "License"
+                ],
+                "summary": "Get license price",
+                "responses": {
+                    "200": {
+                        "description": "return the price",
+                        "schema": {
+                            "$ref": "#/definitions/server.LicensePrice"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/license/purchase": {
+            "post": {
+                "description": "User pay for license, and the server will check the payment, if valid, server will mint license for the user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "License"
+                ],
+                "summary": "Handle license purchase",
+                "parameters": [
+                    {
+                        "description": "receiver: the buyer; amount: buy how many licenses; value: pay how many wei; txhash: the transaction hash that receiver transfer eth to admin",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/server.MintRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "return the transaction hash of mintLicense",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "request parameter error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/node/amount": {
+            "get": {
+                "description": "Query the amount of the registered nodes in nodelist server",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Node"
+                ],
+                "summary": "Get the amount of all registered nodes",
+                "responses": {
+                    "200": {
+                        "description": "return the nodes amount successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/node/commission/history/{address}": {
+            "get": {
+                "description": "Query every commission rate change of the node, oldest first, support paging",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Node"
+                ],
+                "summary": "Get the commission rate history of a node(paging)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "node address(an ethereum address with prefix '0x')",
+                        "name": "address",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "paging start index (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of items to return per page(default 10)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "return commission rate history successfully",
+                        "schema": {
+                            "$ref": "#/definitions/server.CommissionRateHistories"
+                        }
+                    },
+                    "400": {
+                        "description": "request parameter error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/node/info": {
+            "get": {
+                "description": "Query the information of all nodes, support paging",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Node"
+                ],
+                "summary": "Get all nodes information(paging)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "paging start index (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of items to return per page(default 10)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "flag the nodes that changed their commission rate in the recent days(default 30)",
+                        "name": "days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "return node info list successfully",
+                        "schema": {
+                            "$ref": "#/definitions/server.NodeInfos"
+                        }
+                    },
+                    "400": {
+                        "description": "request parameter error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/node/info/delegation/{address}": {
+            "get": {
+                "description": "Query the information of the nodes that the specific owner has delegated his licenses to",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Node"
+                ],
+                "summary": "Get the nodes information delegated by a specific owner",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "owner address(an ethereum address with prefix '0x')",
+                        "name": "address",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "return delegated nodes information successfully",
+                        "schema": {
+                            "$ref": "#/definitions/server.NodeInfos"
+                        }
+                    },
+                    "400": {
+                        "description": "request parameter error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/node/info/owner/{address}": {
+            "get": {
+                "description": "Query the node information through the owner address who owned the node",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Node"
+                ],
+                "summary": "Get the node information of the owner",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "owner address(an ethereum address with prefix '0x')",
+                        "name": "address",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "return the node information successfully",
+                        "schema": {
+                            "$ref": "#/definitions/server.NodeInfo"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/node/info/recipient/{address}": {
+            "get": {
+                "description": "Query the nodes information list through the recipient address who receives the node reward",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Node"
+                ],
+                "summary": "Get the nodes information by the recipient",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "recipient address(an ethereum address with prefix '0x')",
+                        "name": "address",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "paging start index (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of items to return per page(default 10)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "return the nodes information list successfully",
+                        "schema": {
+                            "$ref": "#/definitions/server.NodeInfos"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/node/{idOrAddress}": {
+            "get": {
+                "description": "Query a node by its id or address: its information including the commission rate and rewards, the licenses delegated to it(support paging) and their amount, how many owners delegate to it, and its daily delegation amounts in the recent days, oldest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Node"
+                ],
+                "summary": "Get the detail of a node",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "node id, or node address(an ethereum address with prefix '0x')",
+                        "name": "idOrAddress",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "paging start index of the licenses (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of licenses to return per page(default 10)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of recent days of the daily delegation amounts(default 30)",
+                        "name": "days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "return node detail successfully",
+                        "schema": {
+                            "$ref": "#/definitions/server.NodeDetail"
+                        }
+                    },
+                    "400": {
+                        "description": "request parameter error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "node not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/reward/info/{address}": {
+            "get": {
+                "description": "Query all license rewards and node reward information of the specific owner, include total and withdrawed",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reward"
+                ],
+                "summary": "Get the account's reward information",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "owner address(an ethereum address with prefix '0x')",
+                        "name": "address",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "return reward information successfully",
+                        "schema": {
+                            "$ref": "#/definitions/server.RewardInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "request parameter error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/reward/redeem/history/{address}": {
+            "get": {
+                "description": "Query the redeems of specific owner newest first, with their amounts, lock duration, unlock date and status, support paging. Status is one of locked, claimable, claimed and canceled, redeems in every status are returned if it is not given.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Redeem"
+                ],
+                "summary": "Get the account's redeem history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "owner address(an ethereum address with prefix '0x')",
+                        "name": "address",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "locked",
+                            "claimable",
+                            "claimed",
+                            "canceled"
+                        ],
+                        "type": "string",
+                        "description": "only return redeems in this status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "paging start index (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of items to return per page(default 10)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "return redeem history successfully",
+                        "schema": {
+                            "$ref": "#/definitions/server.RedeemHistories"
+                        }
+                    },
+                    "400": {
+                        "description": "request parameter error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/reward/redeem/info/{address}": {
+            "get": {
+                "description": "Query all locked, unlocked, withdrawed MEMOs and redeeming DelMEMOs of specific owner, as well as unclaimed redeemIDs",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Redeem"
+                ],
+                "summary": "Get the account's redeem information",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "owner address(an ethereum address with prefix '0x')",
                         "name": "address",
                         "in": "path",
                         "required": true
@@ -508,9 +1244,81 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "return reward information successfully",
+                        "description": "return redeem information successfully",
                         "schema": {
-                            "$ref": "#/definitions/server.RewardInfo"
+                            "$ref": "#/definitions/server.RedeemInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "request parameter error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/reward/withdraw/history/{address}": {
+            "get": {
+                "description": "Query the withdrawals from the settlement contract to specific receiver newest first, support paging. Kind is reward for RewardWithdraw events and foundation for FoundationWithdraw events, withdrawals of both kinds are returned if it is not given. Timestamp is the block time.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reward"
+                ],
+                "summary": "Get the account's reward withdrawal history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "receiver address(an ethereum address with prefix '0x')",
+                        "name": "address",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "reward",
+                            "foundation"
+                        ],
+                        "type": "string",
+                        "description": "only return withdrawals of this kind",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "paging start index (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of items to return per page(default 10)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "return withdrawal history successfully",
+                        "schema": {
+                            "$ref": "#/definitions/server.RewardWithdrawHistories"
                         }
                     },
                     "400": {
@@ -534,61 +1342,327 @@ const docTemplate = `{
                 }
             }
         },
-        "/reward/redeem/info/{address}": {
-            "get": {
-                "description": "Query all locked, unlocked, withdrawed MEMOs and redeeming DelMEMOs of specific owner, as well as unclaimed redeemIDs",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Redeem"
-                ],
-                "summary": "Get the account's redeem information",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "owner address(an ethereum address with prefix '0x')",
-                        "name": "address",
-                        "in": "path",
-                        "required": true
+        "/reward/withdraw/total/{address}": {
+            "get": {
+                "description": "Query what specific receiver withdrew from the settlement contract by RewardWithdraw events, by FoundationWithdraw events and in total",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reward"
+                ],
+                "summary": "Get the account's reward withdrawal totals",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "receiver address(an ethereum address with prefix '0x')",
+                        "name": "address",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "return withdrawal totals successfully",
+                        "schema": {
+                            "$ref": "#/definitions/server.RewardWithdrawTotal"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/status": {
+            "get": {
+                "description": "Query how far the indexer is behind the chain, when it last dumped successfully, how many events wait in the dead-letter queue, the health of the rpc endpoints and when the eth price was last refreshed. Times are unix seconds, 0 means never. The lag in seconds is the age of the last indexed block.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Chain"
+                ],
+                "summary": "Get the status of the indexer",
+                "responses": {
+                    "200": {
+                        "description": "return the status successfully",
+                        "schema": {
+                            "$ref": "#/definitions/server.Status"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "server.ChainHeight": {
+            "type": "object",
+            "properties": {
+                "blockTag": {
+                    "type": "string",
+                    "example": "latest"
+                },
+                "chainHead": {
+                    "type": "integer",
+                    "example": 1002
+                },
+                "confirmations": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "indexedBlock": {
+                    "type": "integer",
+                    "example": 1000
+                }
+            }
+        },
+        "server.CommissionRateHistories": {
+            "type": "object",
+            "properties": {
+                "infos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/server.CommissionRateHistory"
+                    }
+                }
+            }
+        },
+        "server.CommissionRateHistory": {
+            "type": "object",
+            "properties": {
+                "blockNumber": {
+                    "type": "integer"
+                },
+                "newRate": {
+                    "type": "integer"
+                },
+                "nodeAddress": {
+                    "type": "string"
+                },
+                "oldRate": {
+                    "type": "integer"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "txHash": {
+                    "type": "string"
+                }
+            }
+        },
+        "server.DelMEMOHolder": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "balance": {
+                    "type": "string",
+                    "example": "1000000000000000000"
+                },
+                "redeeming": {
+                    "type": "string",
+                    "example": "0"
+                }
+            }
+        },
+        "server.DelMEMOHolders": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "infos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/server.DelMEMOHolder"
+                    }
+                }
+            }
+        },
+        "server.DelMEMOSupply": {
+            "type": "object",
+            "properties": {
+                "canceled": {
+                    "type": "string",
+                    "example": "50000"
+                },
+                "canceledRedeems": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "circulating": {
+                    "type": "string",
+                    "example": "750000"
+                },
+                "claimed": {
+                    "type": "string",
+                    "example": "150000"
+                },
+                "minted": {
+                    "type": "string",
+                    "example": "1000000"
+                },
+                "redeemed": {
+                    "type": "string",
+                    "example": "300000"
+                },
+                "serviceFees": {
+                    "type": "string",
+                    "example": "10000"
+                }
+            }
+        },
+        "server.EventLog": {
+            "type": "object",
+            "properties": {
+                "args": {
+                    "type": "string",
+                    "example": "{\"from\":\"0x...\",\"to\":\"0x...\",\"value\":\"1000\"}"
+                },
+                "blockNumber": {
+                    "type": "integer"
+                },
+                "contract": {
+                    "type": "string"
+                },
+                "eventName": {
+                    "type": "string"
+                },
+                "logIndex": {
+                    "type": "integer"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "txHash": {
+                    "type": "string"
+                }
+            }
+        },
+        "server.EventLogs": {
+            "type": "object",
+            "properties": {
+                "infos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/server.EventLog"
+                    }
+                }
+            }
+        },
+        "server.FailedEvent": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "blockNumber": {
+                    "type": "integer"
+                },
+                "contract": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "eventName": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "logIndex": {
+                    "type": "integer"
+                },
+                "nextRetry": {
+                    "type": "integer"
+                },
+                "resolved": {
+                    "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "txHash": {
+                    "type": "string"
+                }
+            }
+        },
+        "server.FailedEvents": {
+            "type": "object",
+            "properties": {
+                "infos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/server.FailedEvent"
                     }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "return redeem information successfully",
-                        "schema": {
-                            "$ref": "#/definitions/server.RedeemInfo"
-                        }
-                    },
-                    "400": {
-                        "description": "request parameter error",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "500": {
-                        "description": "internal server error",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
+                }
+            }
+        },
+        "server.LicenseDelegationHistories": {
+            "type": "object",
+            "properties": {
+                "infos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/server.LicenseDelegationHistory"
                     }
                 }
             }
-        }
-    },
-    "definitions": {
+        },
+        "server.LicenseDelegationHistory": {
+            "type": "object",
+            "properties": {
+                "blockNumber": {
+                    "type": "integer"
+                },
+                "fromNode": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "toNode": {
+                    "type": "string"
+                },
+                "tokenID": {
+                    "type": "string"
+                },
+                "txHash": {
+                    "type": "string"
+                }
+            }
+        },
         "server.LicenseInfo": {
             "type": "object",
             "properties": {
+                "burned": {
+                    "type": "boolean"
+                },
                 "delegated": {
                     "type": "boolean"
                 },
@@ -623,6 +1697,40 @@ const docTemplate = `{
                 }
             }
         },
+        "server.LicenseOwnerHistories": {
+            "type": "object",
+            "properties": {
+                "infos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/server.LicenseOwnerHistory"
+                    }
+                }
+            }
+        },
+        "server.LicenseOwnerHistory": {
+            "type": "object",
+            "properties": {
+                "blockNumber": {
+                    "type": "integer"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "tokenID": {
+                    "type": "string"
+                },
+                "txHash": {
+                    "type": "string"
+                }
+            }
+        },
         "server.LicensePrice": {
             "type": "object",
             "properties": {
@@ -655,6 +1763,45 @@ const docTemplate = `{
                 }
             }
         },
+        "server.NodeDailyDelegation": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "type": "integer"
+                },
+                "delegationAmount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "server.NodeDetail": {
+            "type": "object",
+            "properties": {
+                "dailyDelegations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/server.NodeDailyDelegation"
+                    }
+                },
+                "delegatorAmount": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "info": {
+                    "$ref": "#/definitions/server.NodeInfo"
+                },
+                "licenseAmount": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "licenses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/server.LicenseInfo"
+                    }
+                }
+            }
+        },
         "server.NodeInfo": {
             "type": "object",
             "properties": {
@@ -664,6 +1811,9 @@ const docTemplate = `{
                 "commissionRate": {
                     "type": "integer"
                 },
+                "commissionRateChanged": {
+                    "type": "boolean"
+                },
                 "commissionRateLastModifyAt": {
                     "type": "string"
                 },
@@ -713,6 +1863,68 @@ const docTemplate = `{
                 }
             }
         },
+        "server.RPCEndpoint": {
+            "type": "object",
+            "properties": {
+                "healthy": {
+                    "type": "boolean"
+                },
+                "height": {
+                    "type": "integer",
+                    "example": 1002
+                },
+                "inUse": {
+                    "type": "boolean"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://rpc.example.com"
+                }
+            }
+        },
+        "server.RedeemHistories": {
+            "type": "object",
+            "properties": {
+                "infos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/server.RedeemHistory"
+                    }
+                }
+            }
+        },
+        "server.RedeemHistory": {
+            "type": "object",
+            "properties": {
+                "claimAmount": {
+                    "type": "string",
+                    "example": "950"
+                },
+                "initiator": {
+                    "type": "string"
+                },
+                "lockDuration": {
+                    "type": "integer",
+                    "example": 604800
+                },
+                "redeemAmount": {
+                    "type": "string",
+                    "example": "1000"
+                },
+                "redeemID": {
+                    "type": "string",
+                    "example": "1"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "locked"
+                },
+                "unlockDate": {
+                    "type": "integer",
+                    "example": 1735689600
+                }
+            }
+        },
         "server.RedeemInfo": {
             "type": "object",
             "properties": {
@@ -764,6 +1976,108 @@ const docTemplate = `{
                     "example": "100000"
                 }
             }
+        },
+        "server.RewardWithdrawHistories": {
+            "type": "object",
+            "properties": {
+                "infos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/server.RewardWithdrawHistory"
+                    }
+                }
+            }
+        },
+        "server.RewardWithdrawHistory": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "1000"
+                },
+                "blockNumber": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "kind": {
+                    "type": "string",
+                    "example": "reward"
+                },
+                "receiver": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer",
+                    "example": 1735689600
+                },
+                "txHash": {
+                    "type": "string"
+                }
+            }
+        },
+        "server.RewardWithdrawTotal": {
+            "type": "object",
+            "properties": {
+                "foundationAmount": {
+                    "type": "string",
+                    "example": "0"
+                },
+                "rewardAmount": {
+                    "type": "string",
+                    "example": "1000"
+                },
+                "totalAmount": {
+                    "type": "string",
+                    "example": "1000"
+                }
+            }
+        },
+        "server.Status": {
+            "type": "object",
+            "properties": {
+                "chainHead": {
+                    "type": "integer",
+                    "example": 1002
+                },
+                "ethPriceRefreshed": {
+                    "type": "integer",
+                    "example": 1700000000
+                },
+                "failedEvents": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "indexedBlock": {
+                    "type": "integer",
+                    "example": 1000
+                },
+                "lagBlocks": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "lagSeconds": {
+                    "type": "integer",
+                    "example": 24
+                },
+                "lastDump": {
+                    "type": "integer",
+                    "example": 1700000000
+                },
+                "rpcEndpoints": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/server.RPCEndpoint"
+                    }
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "description": "\"Bearer \" followed by the ADMIN_TOKEN of the server",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
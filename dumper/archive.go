@@ -0,0 +1,108 @@
+package dumper
+
+import (
+	"encoding/json"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/Me-Nodeslist/database/database"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"gorm.io/gorm"
+)
+
+// archiveEvent stores the raw log and its decoded arguments
+func (d *Dumper) archiveEvent(tx *gorm.DB, log types.Log, blockTime uint64) error {
+	topics := make([]string, 0, len(log.Topics))
+	for _, topic := range log.Topics {
+		topics = append(topics, topic.Hex())
+	}
+
+	info := database.EventLog{
+		Contract:    log.Address.Hex(),
+		BlockNumber: int64(log.BlockNumber),
+		BlockHash:   log.BlockHash.Hex(),
+		TxHash:      log.TxHash.Hex(),
+		TxIndex:     log.TxIndex,
+		LogIndex:    log.Index,
+		Timestamp:   int64(blockTime),
+		Topics:      strings.Join(topics, ","),
+		Data:        hexutil.Encode(log.Data),
+	}
+
+	contractIndex, ok := d.contractIndex[log.Address]
+	if ok && len(log.Topics) > 0 {
+		event, err := d.contractABI[contractIndex].EventByID(log.Topics[0])
+		if err == nil {
+			info.EventName = event.Name
+			args, addresses, err := decodeEventArgs(event, log)
+			if err != nil {
+				logger.Debugf("decode %s args of tx %s failed: %s", event.Name, log.TxHash.Hex(), err)
+			} else {
+				info.Args = args
+				info.Addresses = addresses
+			}
+		}
+	}
+
+	return info.CreateEventLog(tx)
+}
+
+// decodeEventArgs unpacks the indexed and non-indexed arguments of the log into json,
+// together with the distinct addresses among them
+func decodeEventArgs(event *abi.Event, log types.Log) (string, []string, error) {
+	args := make(map[string]interface{})
+	err := event.Inputs.NonIndexed().UnpackIntoMap(args, log.Data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var indexed abi.Arguments
+	for _, arg := range event.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	err = abi.ParseTopicsIntoMap(args, indexed, log.Topics[1:])
+	if err != nil {
+		return "", nil, err
+	}
+
+	var addresses []string
+	for _, arg := range event.Inputs {
+		address, ok := args[arg.Name].(common.Address)
+		if ok && !slices.Contains(addresses, address.Hex()) {
+			addresses = append(addresses, address.Hex())
+		}
+	}
+
+	for name, value := range args {
+		args[name] = jsonValue(value)
+	}
+	res, err := json.Marshal(args)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(res), addresses, nil
+}
+
+// jsonValue keeps big numbers and addresses readable in json
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *big.Int:
+		return v.String()
+	case common.Address:
+		return v.Hex()
+	case common.Hash:
+		return v.Hex()
+	case [32]byte:
+		return common.Hash(v).Hex()
+	case []byte:
+		return hexutil.Encode(v)
+	default:
+		return v
+	}
+}
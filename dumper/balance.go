@@ -0,0 +1,195 @@
+package dumper
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/Me-Nodeslist/database/database"
+	"github.com/Me-Nodeslist/database/metrics"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const DEFAULT_RECONCILE_INTERVAL = time.Hour
+const RECONCILE_BATCH_SIZE = 500 // how many balance rows to load at once
+
+// ReconcileBalances compares the DelMEMO balances with the contract periodically
+func (d *Dumper) ReconcileBalances(ctx context.Context, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		err := d.reconcileBalances()
+		if err != nil {
+			logger.Error("reconcile DelMEMO balances err: ", err.Error())
+		}
+	}
+}
+
+// reconcileBalances asks the contract for the balance of every holder at the last indexed
+// block and corrects the ones that differ, then checks their sum against the total supply.
+// Balances changed by a block after it are left for the next round.
+func (d *Dumper) reconcileBalances() error {
+	blockNumber := d.IndexedBlock()
+	if blockNumber < 0 {
+		return nil
+	}
+	number := big.NewInt(blockNumber)
+	header, err := d.client.HeaderByNumber(number)
+	if err != nil {
+		return err
+	}
+	tag := database.BlockTag{
+		BlockNumber: blockNumber,
+		BlockHash:   header.Hash().Hex(),
+		Timestamp:   int64(header.Time),
+	}
+
+	sum := big.NewInt(0)
+	exact := true
+	mismatches := 0
+	for offset := 0; ; offset += RECONCILE_BATCH_SIZE {
+		infos, err := database.GetDelMEMOBalances(offset, RECONCILE_BATCH_SIZE)
+		if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			if info.BlockNumber > blockNumber {
+				exact = false
+				continue
+			}
+			balance, err := d.callDelMEMO(number, "balanceOf", common.HexToAddress(info.Address))
+			if err != nil {
+				return err
+			}
+			sum.Add(sum, balance)
+			if balance.String() == info.Balance {
+				continue
+			}
+
+			corrected, err := database.CorrectDelMEMOBalance(info.Address, balance, tag)
+			if err != nil {
+				return err
+			}
+			if corrected {
+				logger.Warnf("DelMEMO balance of %s is %s at block %d, but %s was indexed", info.Address, balance, blockNumber, info.Balance)
+				metrics.BalanceMismatches.Inc()
+				mismatches++
+			}
+		}
+		if len(infos) < RECONCILE_BATCH_SIZE {
+			break
+		}
+	}
+
+	// the sum is only comparable when no balance was skipped
+	if exact {
+		totalSupply, err := d.callDelMEMO(number, "totalSupply")
+		if err != nil {
+			return err
+		}
+		if totalSupply.Cmp(sum) != 0 {
+			logger.Warnf("DelMEMO total supply is %s at block %d, but the indexed balances add up to %s", totalSupply, blockNumber, sum)
+		}
+	}
+	logger.Infof("reconciled DelMEMO balances at block %d, %d corrected", blockNumber, mismatches)
+	return nil
+}
+
+// seedBalances fills the empty balance ledger of a database that indexed DelMEMO before the
+// ledger existed. The balance of every known account is read from the contract at the last
+// indexed block and the open redeems are added up, the events after the block apply on top.
+func (d *Dumper) seedBalances() error {
+	infos, err := database.GetDelMEMOBalances(0, 1)
+	if err != nil || len(infos) > 0 {
+		return err
+	}
+	blockNumber := d.IndexedBlock()
+	if blockNumber < 0 {
+		return nil
+	}
+	accounts, err := database.GetDelMEMOAccounts(database.GlobalDataBase)
+	if err != nil || len(accounts) == 0 {
+		return err
+	}
+	redeems, err := database.GetOpenRedeemInfos(database.GlobalDataBase)
+	if err != nil {
+		return err
+	}
+
+	redeeming := make(map[common.Address]*big.Int)
+	for _, redeem := range redeems {
+		amount, ok := new(big.Int).SetString(redeem.RedeemAmount, 10)
+		if !ok {
+			return errors.New("invalid amount " + redeem.RedeemAmount + " of redeem " + redeem.RedeemID)
+		}
+		initiator := common.HexToAddress(redeem.Initiator)
+		if redeeming[initiator] == nil {
+			redeeming[initiator] = big.NewInt(0)
+		}
+		redeeming[initiator].Add(redeeming[initiator], amount)
+	}
+
+	number := big.NewInt(blockNumber)
+	header, err := d.client.HeaderByNumber(number)
+	if err != nil {
+		return err
+	}
+	tag := database.BlockTag{
+		BlockNumber: blockNumber,
+		BlockHash:   header.Hash().Hex(),
+		Timestamp:   int64(header.Time),
+	}
+
+	zero := common.Address{}
+	for _, account := range accounts {
+		holder := common.HexToAddress(account)
+		if holder == zero {
+			continue
+		}
+		balance, err := d.callDelMEMO(number, "balanceOf", holder)
+		if err != nil {
+			return err
+		}
+		amount := redeeming[holder]
+		if amount == nil {
+			amount = big.NewInt(0)
+		}
+		infos = append(infos, database.DelMEMOBalance{
+			Address:   holder.Hex(),
+			Balance:   balance.String(),
+			Redeeming: amount.String(),
+			BlockTag:  tag,
+		})
+	}
+	if len(infos) == 0 {
+		return nil
+	}
+	logger.Warnf("seeded the DelMEMO balances of %d accounts at block %d", len(infos), blockNumber)
+	return database.SeedDelMEMOBalances(infos)
+}
+
+// callDelMEMO calls a view method of the DelMEMO contract that returns an amount
+func (d *Dumper) callDelMEMO(blockNumber *big.Int, method string, args ...interface{}) (*big.Int, error) {
+	data, err := d.contractABI[DEL_MEMO].Pack(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	res, err := d.client.CallContract(ethereum.CallMsg{
+		To:   &d.contractAddress[DEL_MEMO],
+		Data: data,
+	}, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	out, err := d.contractABI[DEL_MEMO].Unpack(method, res)
+	if err != nil {
+		return nil, err
+	}
+	return out[0].(*big.Int), nil
+}
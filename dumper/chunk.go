@@ -0,0 +1,51 @@
+package dumper
+
+import (
+	"strings"
+)
+
+const DEFAULT_CHUNK_SIZE = 2000
+const SPARSE_EVENT_AMOUNT = 100 // grow the chunk when a range has fewer events than this
+
+// messages returned by rpc providers when a log query covers too many blocks or results
+var rangeErrorMessages = []string{
+	"query returned more than",
+	"too many results",
+	"block range",
+	"range is too large",
+	"range too large",
+	"response size",
+	"exceeds limit",
+	"limit exceeded",
+	"query timeout",
+}
+
+// messages returned by rpc providers when they throttle us
+var rateLimitErrorMessages = []string{
+	"429",
+	"too many requests",
+	"rate limit",
+	"exceeded the quota",
+	"capacity exceeded",
+}
+
+func isRangeError(err error) bool {
+	return containsAny(err, rangeErrorMessages) && !isRateLimitError(err)
+}
+
+func isRateLimitError(err error) bool {
+	return containsAny(err, rateLimitErrorMessages)
+}
+
+func containsAny(err error, messages []string) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, m := range messages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package dumper
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestChunkErrorClassification(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		rangeErr  bool
+		rateLimit bool
+		retryable bool
+	}{
+		{"nil", nil, false, false, false},
+		{"too many results", errors.New("query returned more than 10000 results"), true, false, false},
+		{"block range", errors.New("eth_getLogs block range is too large"), true, false, false},
+		{"limit exceeded", errors.New("Log response size exceeded. Limit exceeded"), true, false, false},
+		{"http 429", errors.New("429 Too Many Requests"), false, true, true},
+		{"rate limit with exceeds limit", errors.New("rate limit exceeds limit"), false, true, true},
+		{"quota", errors.New("You have exceeded the quota"), false, true, true},
+		{"server error", rpc.HTTPError{StatusCode: 502, Status: "502 Bad Gateway"}, false, false, true},
+		{"client error", rpc.HTTPError{StatusCode: 400, Status: "400 Bad Request"}, false, false, false},
+		{"deadline", context.DeadlineExceeded, false, false, true},
+		{"canceled", context.Canceled, false, false, false},
+		{"not found", ethereum.NotFound, false, false, false},
+		{"header not found", errors.New("header not found"), false, false, true},
+		{"execution reverted", errors.New("execution reverted"), false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRangeError(tt.err); got != tt.rangeErr {
+				t.Errorf("isRangeError(%v) = %v, want %v", tt.err, got, tt.rangeErr)
+			}
+			if got := isRateLimitError(tt.err); got != tt.rateLimit {
+				t.Errorf("isRateLimitError(%v) = %v, want %v", tt.err, got, tt.rateLimit)
+			}
+			if got := isRetryableError(tt.err); got != tt.retryable {
+				t.Errorf("isRetryableError(%v) = %v, want %v", tt.err, got, tt.retryable)
+			}
+		})
+	}
+}
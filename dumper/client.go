@@ -0,0 +1,502 @@
+package dumper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"math/rand/v2"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Me-Nodeslist/database/metrics"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const DEFAULT_RPC_TIMEOUT = 15 * time.Second
+const MAX_RPC_RETRY = 5
+const RPC_BACKOFF_BASE = 500 * time.Millisecond
+const RPC_BACKOFF_MAX = 30 * time.Second
+const RPC_HEALTH_INTERVAL = 30 * time.Second
+const MAX_HEALTHY_LAG = 10 // an endpoint further behind the highest one is unhealthy
+
+// messages of errors that usually go away when the call is repeated
+var transientErrorMessages = []string{
+	"connection reset",
+	"connection refused",
+	"broken pipe",
+	"i/o timeout",
+	"timeout",
+	"eof",
+	"no such host",
+	"service unavailable",
+	"bad gateway",
+	"gateway timeout",
+	"header not found",
+	"try again",
+}
+
+// rpcEndpoint is one rpc provider of the client
+type rpcEndpoint struct {
+	url     string
+	client  *ethclient.Client
+	healthy bool
+	height  uint64
+}
+
+// Client is a long lived rpc connection shared by the dumper. Every call gets its own
+// timeout, transient errors are retried with exponential backoff and jitter, and the
+// connection is dialed again after network errors.
+// With several endpoints the first healthy one is used, and calls fail over to the
+// next one when it returns transient errors.
+type Client struct {
+	timeout time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	lk        sync.Mutex
+	endpoints []*rpcEndpoint
+	current   int
+}
+
+func NewClient(urls []string, timeout time.Duration) (*Client, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no rpc endpoint")
+	}
+	if timeout == 0 {
+		timeout = DEFAULT_RPC_TIMEOUT
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{
+		timeout: timeout,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	for _, url := range urls {
+		c.endpoints = append(c.endpoints, &rpcEndpoint{url: url, healthy: true})
+	}
+	return c, nil
+}
+
+// Close stops pending retries and closes the connections
+func (c *Client) Close() {
+	c.cancel()
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	for _, e := range c.endpoints {
+		if e.client != nil {
+			e.client.Close()
+			e.client = nil
+		}
+	}
+}
+
+// conn returns the connection of the endpoint in use, dialing a new one if there is none
+func (c *Client) conn() (int, *ethclient.Client, error) {
+	c.lk.Lock()
+	index := c.current
+	c.lk.Unlock()
+	client, err := c.dial(index)
+	return index, client, err
+}
+
+// dial connects to the endpoint if it is not connected yet. lk is not held while dialing,
+// so a slow endpoint does not block the others; when two calls dial at once the first
+// connection is kept and the other one is closed.
+func (c *Client) dial(index int) (*ethclient.Client, error) {
+	e := c.endpoints[index]
+	c.lk.Lock()
+	client := e.client
+	c.lk.Unlock()
+	if client != nil {
+		return client, nil
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, c.timeout)
+	defer cancel()
+	client, err := ethclient.DialContext(ctx, e.url)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	if c.ctx.Err() != nil {
+		client.Close()
+		return nil, c.ctx.Err()
+	}
+	if e.client != nil {
+		client.Close()
+		return e.client, nil
+	}
+	e.client = client
+	return client, nil
+}
+
+// failover marks the endpoint unhealthy, drops its connection after network errors and
+// moves on to the next endpoint. It returns whether another healthy endpoint is used now.
+func (c *Client) failover(index int, err error) bool {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	e := c.endpoints[index]
+	if isNetworkError(err) && e.client != nil {
+		e.client.Close()
+		e.client = nil
+	}
+	if len(c.endpoints) == 1 || index != c.current {
+		return index != c.current
+	}
+	e.healthy = false
+
+	for i := 1; i < len(c.endpoints); i++ {
+		next := (index + i) % len(c.endpoints)
+		if c.endpoints[next].healthy {
+			logger.Warnf("fail over from rpc %d to rpc %d: %s", index, next, err)
+			c.current = next
+			return true
+		}
+	}
+	// nothing is known to be healthy, keep trying the others in turn
+	c.current = (index + 1) % len(c.endpoints)
+	return false
+}
+
+// CheckHealth asks all endpoints at once for their latest block, the ones that fail or fall behind
+// are unhealthy, and the first healthy endpoint in the configured order is used
+func (c *Client) CheckHealth() {
+	heights := make([]uint64, len(c.endpoints))
+	errs := make([]error, len(c.endpoints))
+	var wg sync.WaitGroup
+	for i := range c.endpoints {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			client, err := c.dial(i)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			ctx, cancel := context.WithTimeout(c.ctx, c.timeout)
+			defer cancel()
+			heights[i], errs[i] = client.BlockNumber(ctx)
+		}(i)
+	}
+	wg.Wait()
+
+	highest := uint64(0)
+	for i := range heights {
+		if errs[i] == nil {
+			highest = max(highest, heights[i])
+		}
+	}
+
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	for i, e := range c.endpoints {
+		e.height = heights[i]
+		e.healthy = errs[i] == nil && heights[i]+MAX_HEALTHY_LAG >= highest
+		if errs[i] != nil {
+			logger.Warnf("rpc %d is unhealthy: %s", i, errs[i])
+		}
+	}
+	for i, e := range c.endpoints {
+		if e.healthy {
+			if i != c.current {
+				logger.Infof("switch to rpc %d", i)
+			}
+			c.current = i
+			return
+		}
+	}
+}
+
+// EndpointStatus is the health of an rpc endpoint as seen by the last health check
+type EndpointStatus struct {
+	URL     string // scheme and host only, the path may carry an api key
+	Healthy bool
+	Height  uint64
+	InUse   bool
+}
+
+// Endpoints returns the health of every endpoint in the configured order
+func (c *Client) Endpoints() []EndpointStatus {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	res := make([]EndpointStatus, 0, len(c.endpoints))
+	for i, e := range c.endpoints {
+		res = append(res, EndpointStatus{
+			URL:     redactURL(e.url),
+			Healthy: e.healthy,
+			Height:  e.height,
+			InUse:   i == c.current,
+		})
+	}
+	return res
+}
+
+// redactURL drops everything of the url but its scheme and host
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "unknown"
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// QuorumTransaction fetches the transaction and its receipt from all endpoints at once and returns
+// them only when at least quorum of them agree on the sender, receiver and value of the
+// transaction as well as on the receipt
+func (c *Client) QuorumTransaction(hash common.Hash, quorum int) (*types.Transaction, *types.Receipt, error) {
+	if quorum > len(c.endpoints) {
+		return nil, nil, fmt.Errorf("quorum %d is larger than the %d rpc endpoints", quorum, len(c.endpoints))
+	}
+	txs := make([]*types.Transaction, len(c.endpoints))
+	receipts := make([]*types.Receipt, len(c.endpoints))
+	var wg sync.WaitGroup
+	for i := range c.endpoints {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			client, err := c.dial(i)
+			if err == nil {
+				txs[i], receipts[i], err = c.transactionFrom(client, hash)
+			}
+			if err != nil {
+				logger.Warnf("get transaction %s from rpc %d: %s", hash.Hex(), i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	votes := make(map[string]int)
+	for i, tx := range txs {
+		if tx == nil {
+			continue
+		}
+		receipt := receipts[i]
+		from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil {
+			logger.Warnf("get sender of transaction %s from rpc %d: %s", hash.Hex(), i, err)
+			continue
+		}
+		to := ""
+		if tx.To() != nil {
+			to = tx.To().Hex()
+		}
+		key := fmt.Sprintf("%s-%s-%s-%s-%d-%s-%d-%d", tx.Hash().Hex(), from.Hex(), to, tx.Value(), receipt.Status, receipt.BlockHash.Hex(), receipt.GasUsed, len(receipt.Logs))
+		votes[key]++
+		if votes[key] >= quorum {
+			return tx, receipt, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("less than %d rpc endpoints agree on the transaction %s", quorum, hash.Hex())
+}
+
+// transactionFrom fetches the transaction and its receipt from a single endpoint,
+// a transaction that does not hash to the requested hash is rejected
+func (c *Client) transactionFrom(client *ethclient.Client, hash common.Hash) (*types.Transaction, *types.Receipt, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, c.timeout)
+	defer cancel()
+	tx, _, err := client.TransactionByHash(ctx, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tx.Hash() != hash {
+		return nil, nil, fmt.Errorf("got transaction %s", tx.Hash().Hex())
+	}
+	receipt, err := client.TransactionReceipt(ctx, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tx, receipt, nil
+}
+
+// backoff returns how long to wait before the given retry, half fixed and half random
+func backoff(retry int) time.Duration {
+	wait := min(RPC_BACKOFF_BASE<<retry, RPC_BACKOFF_MAX)
+	return wait/2 + rand.N(wait/2+1)
+}
+
+// callRPC runs fn with a per call timeout and retries it while the error is transient,
+// failing over to the next endpoint first if there is a healthy one
+func callRPC[T any](c *Client, name string, fn func(ctx context.Context, client *ethclient.Client) (T, error)) (T, error) {
+	var res T
+	maxRetry := MAX_RPC_RETRY + len(c.endpoints) - 1
+	for retry, waits := 0, 0; ; retry++ {
+		index, client, err := c.conn()
+		if err == nil {
+			start := time.Now()
+			ctx, cancel := context.WithTimeout(c.ctx, c.timeout)
+			res, err = fn(ctx, client)
+			cancel()
+			result := "ok"
+			if err != nil {
+				result = "error"
+			}
+			metrics.RPCDuration.WithLabelValues(name, result).Observe(time.Since(start).Seconds())
+			if err == nil {
+				return res, nil
+			}
+		}
+		if !isRetryableError(err) || retry >= maxRetry || c.ctx.Err() != nil {
+			return res, err
+		}
+		if c.failover(index, err) {
+			continue
+		}
+
+		wait := backoff(waits)
+		waits++
+		logger.Warnf("rpc %s failed, retry after %s: %s", name, wait, err)
+		select {
+		case <-c.ctx.Done():
+			return res, c.ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// isRetryableError tells whether a call may succeed when it is repeated. Errors returned
+// by the node for the request itself (reverted calls, invalid params, missing data,
+// too large log ranges) are permanent.
+func isRetryableError(err error) bool {
+	if err == nil || errors.Is(err, ethereum.NotFound) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if isRateLimitError(err) || isNetworkError(err) {
+		return true
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500
+	}
+	if isRangeError(err) {
+		return false
+	}
+	return containsAny(err, transientErrorMessages)
+}
+
+// isNetworkError tells whether the connection itself failed
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.As(err, &netErr) ||
+		strings.Contains(strings.ToLower(err.Error()), "connection reset")
+}
+
+func (c *Client) BlockNumber() (uint64, error) {
+	return callRPC(c, "BlockNumber", func(ctx context.Context, client *ethclient.Client) (uint64, error) {
+		return client.BlockNumber(ctx)
+	})
+}
+
+func (c *Client) HeaderByNumber(number *big.Int) (*types.Header, error) {
+	return callRPC(c, "HeaderByNumber", func(ctx context.Context, client *ethclient.Client) (*types.Header, error) {
+		return client.HeaderByNumber(ctx, number)
+	})
+}
+
+// HeadersByNumber fetches the headers of the blocks in one json-rpc batch request
+func (c *Client) HeadersByNumber(numbers []uint64) ([]*types.Header, error) {
+	return callRPC(c, "HeadersByNumber", func(ctx context.Context, client *ethclient.Client) ([]*types.Header, error) {
+		headers := make([]*types.Header, len(numbers))
+		batch := make([]rpc.BatchElem, len(numbers))
+		for i, number := range numbers {
+			batch[i] = rpc.BatchElem{
+				Method: "eth_getBlockByNumber",
+				Args:   []interface{}{hexutil.EncodeUint64(number), false},
+				Result: &headers[i],
+			}
+		}
+		err := client.Client().BatchCallContext(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for i, elem := range batch {
+			if elem.Error != nil {
+				return nil, elem.Error
+			}
+			if headers[i] == nil {
+				return nil, fmt.Errorf("header of block %d: %w", numbers[i], ethereum.NotFound)
+			}
+		}
+		return headers, nil
+	})
+}
+
+func (c *Client) FilterLogs(q ethereum.FilterQuery) ([]types.Log, error) {
+	logs, err := callRPC(c, "FilterLogs", func(ctx context.Context, client *ethclient.Client) ([]types.Log, error) {
+		return client.FilterLogs(ctx, q)
+	})
+	if err == nil {
+		metrics.FilterLogsSize.Observe(float64(len(logs)))
+	}
+	return logs, err
+}
+
+func (c *Client) CallContract(msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return callRPC(c, "CallContract", func(ctx context.Context, client *ethclient.Client) ([]byte, error) {
+		return client.CallContract(ctx, msg, blockNumber)
+	})
+}
+
+func (c *Client) TransactionByHash(hash common.Hash) (*types.Transaction, bool, error) {
+	isPending := false
+	tx, err := callRPC(c, "TransactionByHash", func(ctx context.Context, client *ethclient.Client) (*types.Transaction, error) {
+		tx, pending, err := client.TransactionByHash(ctx, hash)
+		isPending = pending
+		return tx, err
+	})
+	return tx, isPending, err
+}
+
+func (c *Client) TransactionReceipt(hash common.Hash) (*types.Receipt, error) {
+	return callRPC(c, "TransactionReceipt", func(ctx context.Context, client *ethclient.Client) (*types.Receipt, error) {
+		return client.TransactionReceipt(ctx, hash)
+	})
+}
+
+func (c *Client) PendingNonceAt(account common.Address) (uint64, error) {
+	return callRPC(c, "PendingNonceAt", func(ctx context.Context, client *ethclient.Client) (uint64, error) {
+		return client.PendingNonceAt(ctx, account)
+	})
+}
+
+func (c *Client) SuggestGasPrice() (*big.Int, error) {
+	return callRPC(c, "SuggestGasPrice", func(ctx context.Context, client *ethclient.Client) (*big.Int, error) {
+		return client.SuggestGasPrice(ctx)
+	})
+}
+
+func (c *Client) NetworkID() (*big.Int, error) {
+	return callRPC(c, "NetworkID", func(ctx context.Context, client *ethclient.Client) (*big.Int, error) {
+		return client.NetworkID(ctx)
+	})
+}
+
+// SendTransaction is not retried, a send that timed out may still have reached the node
+func (c *Client) SendTransaction(tx *types.Transaction) error {
+	_, client, err := c.conn()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, c.timeout)
+	defer cancel()
+	return client.SendTransaction(ctx, tx)
+}
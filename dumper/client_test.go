@@ -0,0 +1,35 @@
+package dumper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckHealthInParallel(t *testing.T) {
+	const delay = 200 * time.Millisecond
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewClient([]string{srv.URL, srv.URL, srv.URL}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	start := time.Now()
+	c.CheckHealth()
+	if elapsed := time.Since(start); elapsed >= 2*delay {
+		t.Errorf("health check of 3 endpoints took %s, they should be asked at once", elapsed)
+	}
+	for i, e := range c.Endpoints() {
+		if !e.Healthy || e.Height != 16 {
+			t.Errorf("rpc %d is %+v, want healthy at height 16", i, e)
+		}
+	}
+}
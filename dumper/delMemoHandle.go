@@ -1,11 +1,13 @@
 package dumper
 
 import (
+	"errors"
 	"math/big"
 
 	"github.com/Me-Nodeslist/database/database"
+	"github.com/Me-Nodeslist/database/metrics"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/core/types"
+	"gorm.io/gorm"
 )
 
 type DelMEMOTransfer struct {
@@ -37,49 +39,77 @@ type ClaimEvent struct {
 	Amount   *big.Int
 }
 
-func (d *Dumper) HandleDelMemoMint(log types.Log) error {
-	var out MintEvent
-	err := d.unpack(log, 1, &out)
-	if err != nil {
-		return err
-	}
+func init() {
+	RegisterHandler(DEL_MEMO, "Transfer", DelMEMOTransfer{}, (*Dumper).HandleDelMemoTransfer)
+	RegisterHandler(DEL_MEMO, "Mint", MintEvent{}, (*Dumper).HandleDelMemoMint)
+	RegisterHandler(DEL_MEMO, "Redeem", RedeemEvent{}, (*Dumper).HandleDelMemoRedeem)
+	RegisterHandler(DEL_MEMO, "CancelRedeem", CancelRedeemEvent{}, (*Dumper).HandleDelMemoCancelRedeem)
+	RegisterHandler(DEL_MEMO, "Claim", ClaimEvent{}, (*Dumper).HandleDelMemoClaim)
+}
+
+func (d *Dumper) HandleDelMemoMint(tx *gorm.DB, event *Event) error {
+	out := event.Args.(*MintEvent)
 
 	// store info to db
 	info := database.DelMEMOMintInfo{
 		Depositer: out.Depositer.Hex(),
 		Receiver:  out.Receiver.Hex(),
 		Amount:    out.Amount.String(),
+		BlockTag:  blockTag(event),
 	}
-	return info.CreateDelMEMOMintInfo()
-}
-
-func (d *Dumper) HandleDelMemoTransfer(log types.Log) error {
-	var out DelMEMOTransfer
-	err := d.unpack(log, 1, &out)
+	err := info.CreateDelMEMOMintInfo(tx)
 	if err != nil {
 		return err
 	}
+	return changeDelMEMOSupply(tx, event, supplyChange{minted: out.Amount})
+}
+
+func (d *Dumper) HandleDelMemoTransfer(tx *gorm.DB, event *Event) error {
+	out := event.Args.(*DelMEMOTransfer)
 
-	if out.From.Hex() == common.BigToAddress(big.NewInt(0)).Hex() {
+	// mints come from and burns go to the zero address
+	zero := common.BigToAddress(big.NewInt(0))
+	if out.From != zero {
+		err := changeDelMEMOBalance(tx, event, out.From, new(big.Int).Neg(out.Value), nil)
+		if err != nil {
+			return err
+		}
+	}
+	if out.To != zero {
+		err := changeDelMEMOBalance(tx, event, out.To, out.Value, nil)
+		if err != nil {
+			return err
+		}
+	}
+	if out.From == zero || out.To == zero {
+		circulating := out.Value
+		if out.To == zero {
+			circulating = new(big.Int).Neg(out.Value)
+		}
+		err := changeDelMEMOSupply(tx, event, supplyChange{circulating: circulating})
+		if err != nil {
+			return err
+		}
+	}
+
+	if out.From == zero {
 		logger.Debug("DelMEMO Transfer event: From is 0")
 		return nil
 	}
 
 	// store info to db
 	info := database.DelMEMOTransferInfo{
-		From:   out.From.Hex(),
-		To:     out.To.Hex(),
-		Amount: out.Value.String(),
+		From:     out.From.Hex(),
+		To:       out.To.Hex(),
+		Amount:   out.Value.String(),
+		BlockTag: blockTag(event),
 	}
-	return info.CreateDelMEMOTransferInfo()
+	return info.CreateDelMEMOTransferInfo(tx)
 }
 
-func (d *Dumper) HandleDelMemoRedeem(log types.Log, time uint64) error {
-	var out RedeemEvent
-	err := d.unpack(log, 1, &out)
-	if err != nil {
-		return err
-	}
+func (d *Dumper) HandleDelMemoRedeem(tx *gorm.DB, event *Event) error {
+	out := event.Args.(*RedeemEvent)
+	time := event.BlockTime
 
 	// store info to db
 	info := database.RedeemInfo{
@@ -89,36 +119,169 @@ func (d *Dumper) HandleDelMemoRedeem(log types.Log, time uint64) error {
 		ClaimAmount:  out.ClaimAmount.String(),
 		LockDuration: out.Duration,
 		UnlockDate:   int64(time) + int64(out.Duration),
+		BlockTag:     blockTag(event),
 	}
-	return info.CreateRedeemInfo()
-}
-
-func (d *Dumper) HandleDelMemoCancelRedeem(log types.Log) error {
-	var out CancelRedeemEvent
-	err := d.unpack(log, 1, &out)
+	err := info.CreateRedeemInfo(tx)
+	if err != nil {
+		return err
+	}
+	err = changeDelMEMOBalance(tx, event, out.Initiator, nil, out.Amount)
 	if err != nil {
 		return err
 	}
+	return changeDelMEMOSupply(tx, event, supplyChange{redeemed: out.Amount})
+}
+
+func (d *Dumper) HandleDelMemoCancelRedeem(tx *gorm.DB, event *Event) error {
+	out := event.Args.(*CancelRedeemEvent)
 
 	// store info to db
 	info := database.RedeemInfo{
 		RedeemID: out.RedeemID.String(),
 		Canceled: true,
+		BlockTag: blockTag(event),
 	}
-	return info.UpdateRedeemInfo()
-}
-
-func (d *Dumper) HandleDelMemoClaim(log types.Log) error {
-	var out ClaimEvent
-	err := d.unpack(log, 1, &out)
+	err := info.UpdateRedeemInfo(tx)
 	if err != nil {
 		return err
 	}
+	amount, err := finishRedeem(tx, event, info.RedeemID)
+	if err != nil {
+		return err
+	}
+	return changeDelMEMOSupply(tx, event, supplyChange{canceled: amount, canceledRedeems: 1})
+}
+
+func (d *Dumper) HandleDelMemoClaim(tx *gorm.DB, event *Event) error {
+	out := event.Args.(*ClaimEvent)
 
 	// store info to db
 	info := database.RedeemInfo{
 		RedeemID: out.RedeemID.String(),
-		Claimed: true,
+		Claimed:  true,
+		BlockTag: blockTag(event),
+	}
+	err := info.UpdateRedeemInfo(tx)
+	if err != nil {
+		return err
+	}
+	amount, err := finishRedeem(tx, event, info.RedeemID)
+	if err != nil {
+		return err
+	}
+	// whatever of the redeemed DelMEMO is not paid out is kept as fee
+	return changeDelMEMOSupply(tx, event, supplyChange{claimed: out.Amount, serviceFees: new(big.Int).Sub(amount, out.Amount)})
+}
+
+// finishRedeem takes the amount of a canceled or claimed redeem off the redeeming amount of its
+// initiator, it returns the redeemed amount
+func finishRedeem(tx *gorm.DB, event *Event, redeemID string) (*big.Int, error) {
+	redeem, err := database.GetRedeemInfo(tx, redeemID)
+	if err != nil {
+		return nil, err
+	}
+	amount, ok := new(big.Int).SetString(redeem.RedeemAmount, 10)
+	if !ok {
+		return nil, errors.New("invalid amount " + redeem.RedeemAmount + " of redeem " + redeemID)
+	}
+	err = changeDelMEMOBalance(tx, event, common.HexToAddress(redeem.Initiator), nil, new(big.Int).Neg(amount))
+	return amount, err
+}
+
+// changeDelMEMOBalance adds balance and redeeming to the amounts of the holder, either may be nil
+func changeDelMEMOBalance(tx *gorm.DB, event *Event, holder common.Address, balance *big.Int, redeeming *big.Int) error {
+	info, err := database.GetDelMEMOBalance(tx, holder.Hex())
+	exist := err == nil
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		info = database.DelMEMOBalance{
+			Address:   holder.Hex(),
+			Balance:   "0",
+			Redeeming: "0",
+		}
+	} else if err != nil {
+		return err
+	}
+
+	newBalance, ok := new(big.Int).SetString(info.Balance, 10)
+	if !ok {
+		return errors.New("invalid DelMEMO balance " + info.Balance + " of " + info.Address)
+	}
+	newRedeeming, ok := new(big.Int).SetString(info.Redeeming, 10)
+	if !ok {
+		return errors.New("invalid DelMEMO redeeming amount " + info.Redeeming + " of " + info.Address)
+	}
+	if balance != nil {
+		newBalance.Add(newBalance, balance)
+	}
+	if redeeming != nil {
+		newRedeeming.Add(newRedeeming, redeeming)
+	}
+	// the holders are sorted by the text of the balance, which only works for non-negative amounts.
+	// A negative amount means the ledger missed an earlier change, the event is still stored and
+	// the amount is kept at zero until the reconciliation reads the balance from the contract.
+	if newBalance.Sign() < 0 || newRedeeming.Sign() < 0 {
+		logger.Warnf("DelMEMO balance %s or redeeming amount %s of %s would be negative at block %d, keep it at zero", newBalance, newRedeeming, info.Address, event.Log.BlockNumber)
+		metrics.BalanceMismatches.Inc()
+		if newBalance.Sign() < 0 {
+			newBalance.SetInt64(0)
+		}
+		if newRedeeming.Sign() < 0 {
+			newRedeeming.SetInt64(0)
+		}
+	}
+	info.Balance = newBalance.String()
+	info.Redeeming = newRedeeming.String()
+	info.BlockTag = blockTag(event)
+
+	if exist {
+		return info.UpdateDelMEMOBalance(tx)
+	}
+	return info.CreateDelMEMOBalance(tx)
+}
+
+// supplyChange is what an event adds to the DelMEMO totals, nil amounts are left as they are
+type supplyChange struct {
+	minted          *big.Int
+	redeemed        *big.Int
+	claimed         *big.Int
+	canceled        *big.Int
+	canceledRedeems int64
+	circulating     *big.Int
+	serviceFees     *big.Int
+}
+
+func changeDelMEMOSupply(tx *gorm.DB, event *Event, change supplyChange) error {
+	info, err := database.GetDelMEMOSupply(tx)
+	if err != nil {
+		return err
+	}
+
+	amounts := []struct {
+		total *string
+		delta *big.Int
+	}{
+		{&info.Minted, change.minted},
+		{&info.Redeemed, change.redeemed},
+		{&info.Claimed, change.claimed},
+		{&info.Canceled, change.canceled},
+		{&info.Circulating, change.circulating},
+		{&info.ServiceFees, change.serviceFees},
+	}
+	for _, amount := range amounts {
+		if amount.delta == nil {
+			continue
+		}
+		total, ok := new(big.Int).SetString(*amount.total, 10)
+		if !ok {
+			return errors.New("invalid DelMEMO total " + *amount.total)
+		}
+		*amount.total = total.Add(total, amount.delta).String()
+	}
+	info.CanceledRedeems += change.canceledRedeems
+	info.BlockTag = blockTag(event)
+
+	if info.ID == 0 {
+		return info.CreateDelMEMOSupply(tx)
 	}
-	return info.UpdateRedeemInfo()
+	return info.UpdateDelMEMOSupply(tx)
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/Me-Nodeslist/database/database"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"gorm.io/gorm"
 )
 
 type ModifyCommissionRateEvent struct {
@@ -49,30 +50,55 @@ type NodeRegisterEvent struct {
 	CommissionRate uint8
 }
 
-func (d *Dumper) HandleModifyCommissionRate(log types.Log, time uint64) error {
-	var out ModifyCommissionRateEvent
-	err := d.unpack(log, 3, &out)
+func init() {
+	RegisterHandler(DELEGATION, "NodeRegister", NodeRegisterEvent{}, (*Dumper).HandleNodeRegister)
+	RegisterHandler(DELEGATION, "ModifyCommissionRate", ModifyCommissionRateEvent{}, (*Dumper).HandleModifyCommissionRate)
+	RegisterHandler(DELEGATION, "NodeWithdraw", NodeWithdrawEvent{}, (*Dumper).HandleNodeWithdraw)
+	RegisterHandler(DELEGATION, "ConfirmNodeReward", ConfirmNodeRewardEvent{}, (*Dumper).HandleConfirmNodeReward)
+	RegisterHandler(DELEGATION, "NodeDailyDelegations", NodeDailyDelegationsEvent{}, (*Dumper).HandleNodeDailyDelegations)
+	RegisterHandler(DELEGATION, "Delegate", DelegateEvent{}, (*Dumper).HandleDelegate)
+	RegisterHandler(DELEGATION, "Undelegate", DelegateEvent{}, (*Dumper).HandleUndelegate)
+	RegisterHandler(DELEGATION, "Redelegate", DelegateEvent{}, (*Dumper).HandleRedelegate)
+	RegisterHandler(DELEGATION, "ClaimReward", ClaimRewardEvent{}, (*Dumper).HandleClaimReward)
+
+	RegisterFetcher(DELEGATION, "NodeRegister", (*Dumper).fetchNodeInfo)
+}
+
+func (d *Dumper) HandleModifyCommissionRate(tx *gorm.DB, event *Event) error {
+	out := event.Args.(*ModifyCommissionRateEvent)
+	log := event.Log
+	time := event.BlockTime
+
+	info, err := database.GetNodeInfoByNodeAddress(tx, out.Node)
+	if err != nil {
+		return err
+	}
+	history := database.CommissionRateHistory{
+		NodeAddress: out.Node.Hex(),
+		OldRate:     info.CommissionRate,
+		NewRate:     out.CommissionRate,
+		TxHash:      log.TxHash.Hex(),
+		BlockTag:    blockTag(event),
+	}
+	err = history.CreateCommissionRateHistory(tx)
 	if err != nil {
 		return err
 	}
 
 	// store info to db
-	info := database.NodeInfo{
+	info = database.NodeInfo{
 		NodeAddress:                out.Node.Hex(),
 		CommissionRate:             out.CommissionRate,
 		CommissionRateLastModifyAt: strconv.FormatUint(time, 10),
+		BlockTag:                   blockTag(event),
 	}
-	return info.UpdateNodeCommissionRate()
+	return info.UpdateNodeCommissionRate(tx)
 }
 
-func (d *Dumper) HandleNodeWithdraw(log types.Log) error {
-	var out NodeWithdrawEvent
-	err := d.unpack(log, 3, &out)
-	if err != nil {
-		return err
-	}
+func (d *Dumper) HandleNodeWithdraw(tx *gorm.DB, event *Event) error {
+	out := event.Args.(*NodeWithdrawEvent)
 
-	info, err := database.GetNodeInfoByNodeAddress(out.Node)
+	info, err := database.GetNodeInfoByNodeAddress(tx, out.Node)
 	if err != nil {
 		return err
 	}
@@ -89,18 +115,15 @@ func (d *Dumper) HandleNodeWithdraw(log types.Log) error {
 		SelfTotalReward:      tr,
 		SelfWithdrawedReward: out.Reward.Add(out.Reward, value).String(),
 		DelegationReward:     dr,
+		BlockTag:             blockTag(event),
 	}
-	return info.UpdateNodeRewardInfo()
+	return info.UpdateNodeRewardInfo(tx)
 }
 
-func (d *Dumper) HandleConfirmNodeReward(log types.Log) error {
-	var out ConfirmNodeRewardEvent
-	err := d.unpack(log, 3, &out)
-	if err != nil {
-		return err
-	}
+func (d *Dumper) HandleConfirmNodeReward(tx *gorm.DB, event *Event) error {
+	out := event.Args.(*ConfirmNodeRewardEvent)
 
-	info, err := database.GetNodeInfoByNodeAddress(out.Node)
+	info, err := database.GetNodeInfoByNodeAddress(tx, out.Node)
 	if err != nil {
 		return err
 	}
@@ -108,13 +131,14 @@ func (d *Dumper) HandleConfirmNodeReward(log types.Log) error {
 	// store info to db
 	info.SelfTotalReward = out.SelfTotalRewards.String()
 	info.DelegationReward = out.DelegationRewards.String()
-	err = info.UpdateNodeRewardInfo()
+	info.BlockTag = blockTag(event)
+	err = info.UpdateNodeRewardInfo(tx)
 	if err != nil {
 		return err
 	}
 
 	// update license reward
-	licenseInfos, err := database.GetLicenseInfosByNode(out.Node, int(0), int(info.DelegationAmount))
+	licenseInfos, err := database.GetLicenseInfosByNode(tx, out.Node, int(0), int(info.DelegationAmount))
 	if err != nil {
 		return err
 	}
@@ -141,67 +165,70 @@ func (d *Dumper) HandleConfirmNodeReward(log types.Log) error {
 		totalReward = totalReward.Add(totalReward, addReward)
 		licenseInfo.TotalReward = totalReward.String()
 		licenseInfo.InitialReward = info.DelegationReward
-		licenseInfo.UpdateLicenseReward()
+		licenseInfo.BlockTag = blockTag(event)
+		err = licenseInfo.UpdateLicenseReward(tx)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (d *Dumper) HandleNodeDailyDelegations(log types.Log) error {
-	var out NodeDailyDelegationsEvent
-	err := d.unpack(log, 3, &out)
-	if err != nil {
-		return err
-	}
+func (d *Dumper) HandleNodeDailyDelegations(tx *gorm.DB, event *Event) error {
+	out := event.Args.(*NodeDailyDelegationsEvent)
 
 	// store info to db
 	info := database.NodeDailyDelegation{
 		NodeAddress:      out.Node.Hex(),
 		Date:             uint16(out.Date),
 		DelegationAmount: out.DelegationAmount,
+		BlockTag:         blockTag(event),
 	}
-	_, err = database.GetNodeDailyDelegation(out.Node, info.Date)
+	_, err := database.GetNodeDailyDelegation(tx, out.Node, info.Date)
 	if err == nil { // exist
-		err = info.UpdateNodeDailyDelegation()
+		err = info.UpdateNodeDailyDelegation(tx)
 	} else {
-		err = info.CreateNodeDailyDelegation()
+		err = info.CreateNodeDailyDelegation(tx)
 	}
 	if err != nil {
 		return err
 	}
 
 	// online days
-	nodeInfo, err := database.GetNodeInfoByNodeAddress(out.Node)
+	nodeInfo, err := database.GetNodeInfoByNodeAddress(tx, out.Node)
 	if err != nil {
 		return err
 	}
-	length_month, length_week, err := database.GetNodeRecentOnlineDays(out.Node, info.Date)
+	length_month, length_week, err := database.GetNodeRecentOnlineDays(tx, out.Node, info.Date)
 	if err != nil {
 		return err
 	}
 	nodeInfo.OnlineDays++
 	nodeInfo.OnlineDays_RecentMonth = length_month
 	nodeInfo.OnlineDays_RecentWeek = length_week
-	return nodeInfo.UpdateNodeOnlineDays()
+	nodeInfo.BlockTag = blockTag(event)
+	return nodeInfo.UpdateNodeOnlineDays(tx)
 }
 
-func (d *Dumper) HandleDelegate(log types.Log) error {
-	var out DelegateEvent
-	err := d.unpack(log, 3, &out)
-	if err != nil {
-		return err
-	}
+func (d *Dumper) HandleDelegate(tx *gorm.DB, event *Event) error {
+	out := event.Args.(*DelegateEvent)
 
 	licenseInfo := database.LicenseInfo{
 		TokenID:       out.TokenID.String(),
 		Delegated:     true,
 		DelegatedNode: out.To.Hex(),
+		BlockTag:      blockTag(event),
 	}
-	err = licenseInfo.UpdateLicenseDelegation()
+	err := licenseInfo.UpdateLicenseDelegation(tx)
+	if err != nil {
+		return err
+	}
+	err = saveDelegationHistory(tx, event, out.TokenID, common.Address{}, out.To)
 	if err != nil {
 		return err
 	}
 
-	info, err := database.GetNodeInfoByNodeAddress(out.To)
+	info, err := database.GetNodeInfoByNodeAddress(tx, out.To)
 	if err != nil {
 		return err
 	}
@@ -212,18 +239,15 @@ func (d *Dumper) HandleDelegate(log types.Log) error {
 		NodeAddress:      out.To.Hex(),
 		DelegationAmount: amount,
 		Active:           true,
+		BlockTag:         blockTag(event),
 	}
-	return info.UpdateNodeDelegationAmount()
+	return info.UpdateNodeDelegationAmount(tx)
 }
 
-func (d *Dumper) HandleUndelegate(log types.Log) error {
-	var out DelegateEvent
-	err := d.unpack(log, 3, &out)
-	if err != nil {
-		return err
-	}
+func (d *Dumper) HandleUndelegate(tx *gorm.DB, event *Event) error {
+	out := event.Args.(*DelegateEvent)
 
-	info, err := database.GetNodeInfoByNodeAddress(out.To)
+	info, err := database.GetNodeInfoByNodeAddress(tx, out.To)
 	if err != nil {
 		return err
 	}
@@ -238,8 +262,9 @@ func (d *Dumper) HandleUndelegate(log types.Log) error {
 		NodeAddress:      out.To.Hex(),
 		DelegationAmount: amount,
 		Active:           active,
+		BlockTag:         blockTag(event),
 	}
-	err = info.UpdateNodeDelegationAmount()
+	err = info.UpdateNodeDelegationAmount(tx)
 	if err != nil {
 		return err
 	}
@@ -247,26 +272,27 @@ func (d *Dumper) HandleUndelegate(log types.Log) error {
 		TokenID:       out.TokenID.String(),
 		Delegated:     false,
 		DelegatedNode: common.BigToAddress(big.NewInt(0)).Hex(),
+		BlockTag:      blockTag(event),
 	}
-	return licenseInfo.UpdateLicenseDelegation()
-}
-
-func (d *Dumper) HandleRedelegate(log types.Log) error {
-	var out DelegateEvent
-	err := d.unpack(log, 3, &out)
+	err = licenseInfo.UpdateLicenseDelegation(tx)
 	if err != nil {
 		return err
 	}
+	return saveDelegationHistory(tx, event, out.TokenID, out.To, common.Address{})
+}
+
+func (d *Dumper) HandleRedelegate(tx *gorm.DB, event *Event) error {
+	out := event.Args.(*DelegateEvent)
 
-	info, err := database.GetNodeInfoByNodeAddress(out.To)
+	info, err := database.GetNodeInfoByNodeAddress(tx, out.To)
 	if err != nil {
 		return err
 	}
-	licenseInfo, err := database.GetLicenseInfoByTokenID(out.TokenID.String())
+	licenseInfo, err := database.GetLicenseInfoByTokenID(tx, out.TokenID.String())
 	if err != nil {
 		return err
 	}
-	infoOld, err := database.GetNodeInfoByNodeAddress(common.HexToAddress(licenseInfo.DelegatedNode))
+	infoOld, err := database.GetNodeInfoByNodeAddress(tx, common.HexToAddress(licenseInfo.DelegatedNode))
 	if err != nil {
 		return err
 	}
@@ -282,8 +308,9 @@ func (d *Dumper) HandleRedelegate(log types.Log) error {
 		NodeAddress:      infoOld.NodeAddress,
 		DelegationAmount: amount,
 		Active:           active,
+		BlockTag:         blockTag(event),
 	}
-	err = nodeInfo.UpdateNodeDelegationAmount()
+	err = nodeInfo.UpdateNodeDelegationAmount(tx)
 	if err != nil {
 		return err
 	}
@@ -295,8 +322,9 @@ func (d *Dumper) HandleRedelegate(log types.Log) error {
 		NodeAddress:      info.NodeAddress,
 		DelegationAmount: amount,
 		Active:           true,
+		BlockTag:         blockTag(event),
 	}
-	err = nodeInfo.UpdateNodeDelegationAmount()
+	err = nodeInfo.UpdateNodeDelegationAmount(tx)
 	if err != nil {
 		return err
 	}
@@ -306,18 +334,32 @@ func (d *Dumper) HandleRedelegate(log types.Log) error {
 		TokenID:       out.TokenID.String(),
 		Delegated:     true,
 		DelegatedNode: out.To.Hex(),
+		BlockTag:      blockTag(event),
 	}
-	return licenseInfo.UpdateLicenseDelegation()
-}
-
-func (d *Dumper) HandleClaimReward(log types.Log) error {
-	var out ClaimRewardEvent
-	err := d.unpack(log, 3, &out)
+	err = licenseInfo.UpdateLicenseDelegation(tx)
 	if err != nil {
 		return err
 	}
+	return saveDelegationHistory(tx, event, out.TokenID, common.HexToAddress(infoOld.NodeAddress), out.To)
+}
+
+// saveDelegationHistory records that the license moved from one node to another,
+// address(0) stands for not delegated
+func saveDelegationHistory(tx *gorm.DB, event *Event, tokenID *big.Int, from common.Address, to common.Address) error {
+	history := database.LicenseDelegationHistory{
+		TokenID:  tokenID.String(),
+		FromNode: from.Hex(),
+		ToNode:   to.Hex(),
+		TxHash:   event.Log.TxHash.Hex(),
+		BlockTag: blockTag(event),
+	}
+	return history.CreateLicenseDelegationHistory(tx)
+}
 
-	info, err := database.GetLicenseInfoByTokenID(out.TokenID.String())
+func (d *Dumper) HandleClaimReward(tx *gorm.DB, event *Event) error {
+	out := event.Args.(*ClaimRewardEvent)
+
+	info, err := database.GetLicenseInfoByTokenID(tx, out.TokenID.String())
 	if err != nil {
 		return err
 	}
@@ -333,24 +375,29 @@ func (d *Dumper) HandleClaimReward(log types.Log) error {
 
 	// store info to db
 	info.WithdrawedReward = amount.String()
-	return info.UpdateLicenseReward()
+	info.BlockTag = blockTag(event)
+	return info.UpdateLicenseReward(tx)
 }
 
 func (d *Dumper) GetNodeAddr(log types.Log) (common.Address, error) {
 	var out NodeRegisterEvent
-	err := d.unpack(log, 3, &out)
+	err := d.unpack(log, DELEGATION, &out)
 	if err != nil {
 		return common.Address{}, err
 	}
 	return out.Node, nil
 }
 
-func (d *Dumper) HandleNodeRegister(log types.Log, time uint64, nodeInfo *database.NodeInfoOnChain) error {
-	var out NodeRegisterEvent
-	err := d.unpack(log, 3, &out)
-	if err != nil {
-		return err
-	}
+// fetchNodeInfo reads the info of the registered node at the block of the event
+func (d *Dumper) fetchNodeInfo(log types.Log) (interface{}, error) {
+	return d.getNodeInfo(d.client, log)
+}
+
+func (d *Dumper) HandleNodeRegister(tx *gorm.DB, event *Event) error {
+	out := event.Args.(*NodeRegisterEvent)
+	time := event.BlockTime
+
+	nodeInfo := event.Fetched.(database.NodeInfoOnChain)
 
 	// store info to db
 	info := database.NodeInfo{
@@ -362,6 +409,7 @@ func (d *Dumper) HandleNodeRegister(log types.Log, time uint64, nodeInfo *databa
 		CommissionRateLastModifyAt: nodeInfo.CommissionRateLastModifyAt.String(),
 		RegisterDate:               strconv.FormatUint(time, 10),
 		ExpireDate:                 strconv.FormatUint(time+94608000, 10), // +3years
+		BlockTag:                   blockTag(event),
 	}
-	return info.CreateNodeInfo()
+	return info.CreateNodeInfo(tx)
 }
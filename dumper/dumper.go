@@ -4,23 +4,28 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"math/big"
 	"net/http"
-	"os"
-	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	contractabi "github.com/Me-Nodeslist/database/abi"
 	"github.com/Me-Nodeslist/database/database"
 	"github.com/Me-Nodeslist/database/logs"
+	"github.com/Me-Nodeslist/database/metrics"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"gorm.io/gorm"
 )
 
 type ContractAddress struct {
@@ -30,15 +35,55 @@ type ContractAddress struct {
 	Delegation common.Address
 }
 
+// Options decides which blocks of the chain the dumper treats as stable
+type Options struct {
+	Confirmations uint64 // how many blocks to stay behind the latest block
+	BlockTag      string // follow "latest", "safe" or "finalized" block
+
+	ChunkSize     uint64        // how many blocks to query logs for at once
+	MaxChunkSize  uint64        // the upper limit the chunk size grows to on sparse ranges
+	ChunkInterval time.Duration // pause between two chunks, keeps backfills under rpc rate limits
+
+	RPCTimeout     time.Duration // timeout of a single rpc call
+	PurchaseQuorum int           // how many rpc endpoints must agree on a purchase transaction
+
+	WSEndpoint string // websocket rpc url to subscribe to new heads, polls when empty
+
+	ABIDir string // directory of the contract abi files, the embedded ones are used when empty
+
+	HaltOnFailure bool // stop at an event whose handler fails instead of queueing it for retry
+}
+
 type Dumper struct {
-	endpoint        string
+	client          *Client
+	headers         *headerCache
 	contractABI     []abi.ABI
 	contractAddress []common.Address
 
+	confirmations uint64
+	blockTag      string
+
+	chunkSize     uint64
+	maxChunkSize  uint64
+	chunkInterval time.Duration
+
+	purchaseQuorum int
+
+	wsEndpoint string
+
+	haltOnFailure bool
+
+	contractIndex map[common.Address]uint8
+
 	blockNumber *big.Int
 
+	// heights reported to clients
+	indexedBlock atomic.Int64
+	indexedTime  atomic.Uint64 // time of the indexed block
+	chainHead    atomic.Uint64
+	lastDump     atomic.Int64 // unix time the last dump finished without error
+
 	eventNameMap map[common.Hash]string
-	indexedMap   map[common.Hash]abi.Arguments
 }
 
 type EtherscanResponse struct {
@@ -55,6 +100,9 @@ type EtherscanResponse struct {
 const LICENSE_PAYMENT_RECEIVER = "0x389824fc8755039F165738139b255Fad711e2bCb"
 const LICENSE_PRICE_USDT = 500
 const PAYMENT_DEVIATION = 0.01 // accept 1% error
+const MAX_REORG_DEPTH = 128    // how many processed blocks can be rolled back
+const POLL_INTERVAL = 10 * time.Second
+const WS_RETRY_INTERVAL = time.Minute // how long to poll before subscribing again
 
 var (
 	// blockNumber = big.NewInt(0)
@@ -63,79 +111,74 @@ var (
 var URL string
 var EthUSD float64
 var EthUSD_Timestamp int
+var EthUSD_RefreshTime int64 // unix time of the last successful price refresh
 
-func NewDumper(ethrpc string, addrs *ContractAddress) (dumper *Dumper, err error) {
+func NewDumper(ethrpc []string, addrs *ContractAddress, opts *Options) (dumper *Dumper, err error) {
 	dumper = &Dumper{
-		eventNameMap: make(map[common.Hash]string),
-		indexedMap:   make(map[common.Hash]abi.Arguments),
-	}
+		eventNameMap:  make(map[common.Hash]string),
+		confirmations: opts.Confirmations,
+		blockTag:      opts.BlockTag,
+		chunkSize:     opts.ChunkSize,
+		maxChunkSize:  opts.MaxChunkSize,
+		chunkInterval: opts.ChunkInterval,
 
-	//_, endpoint := com.GetInsEndPointByChain(chain)
-	dumper.endpoint = ethrpc
+		purchaseQuorum: opts.PurchaseQuorum,
 
-	dumper.contractAddress = []common.Address{addrs.LicenseNFT, addrs.DelMEMO, addrs.Settlement, addrs.Delegation}
+		wsEndpoint: opts.WSEndpoint,
 
-	projectDir, err := filepath.Abs(".")
-	if err != nil {
-		log.Fatal(err)
+		haltOnFailure: opts.HaltOnFailure,
 	}
-	filePath := filepath.Join(projectDir, "abi", "LicenseNFT.abi")
-	licenseNFTABI, err := os.ReadFile(filePath)
-	if err != nil {
-		logger.Error("Failed to read licenseNFT abi file, ", err)
-		return dumper, err
+	if dumper.chunkSize == 0 {
+		dumper.chunkSize = DEFAULT_CHUNK_SIZE
 	}
-	licenseContractABI, err := abi.JSON(strings.NewReader(string(licenseNFTABI)))
-	if err != nil {
-		return dumper, err
+	if dumper.maxChunkSize < dumper.chunkSize {
+		dumper.maxChunkSize = dumper.chunkSize
 	}
-
-	filePath = filepath.Join(projectDir, "abi", "DelMEMO.abi")
-	delMEMOABI, err := os.ReadFile(filePath)
-	if err != nil {
-		logger.Error("Failed to read delMEMO abi file, ", err)
-		return dumper, err
+	switch dumper.blockTag {
+	case "":
+		dumper.blockTag = "latest"
+	case "latest", "safe", "finalized":
+	default:
+		return dumper, errors.New("unsupported block tag " + opts.BlockTag + ", should be latest, safe or finalized")
 	}
-	delMemoContractABI, err := abi.JSON(strings.NewReader(string(delMEMOABI)))
-	if err != nil {
-		return dumper, err
+
+	if dumper.purchaseQuorum > len(ethrpc) {
+		return dumper, fmt.Errorf("purchase quorum %d is larger than the %d rpc endpoints", dumper.purchaseQuorum, len(ethrpc))
 	}
 
-	filePath = filepath.Join(projectDir, "abi", "Settlement.abi")
-	settlementABI, err := os.ReadFile(filePath)
+	//_, endpoint := com.GetInsEndPointByChain(chain)
+	dumper.client, err = NewClient(ethrpc, opts.RPCTimeout)
 	if err != nil {
-		logger.Error("Failed to read settlement abi file, ", err)
 		return dumper, err
 	}
-	settlementContractABI, err := abi.JSON(strings.NewReader(string(settlementABI)))
-	if err != nil {
-		return dumper, err
+	dumper.headers = newHeaderCache(HEADER_CACHE_SIZE)
+
+	dumper.contractAddress = []common.Address{addrs.LicenseNFT, addrs.DelMEMO, addrs.Settlement, addrs.Delegation}
+	dumper.contractIndex = make(map[common.Address]uint8)
+	for i, addr := range dumper.contractAddress {
+		dumper.contractIndex[addr] = uint8(i)
 	}
 
-	filePath = filepath.Join(projectDir, "abi", "Delegation.abi")
-	delegationABI, err := os.ReadFile(filePath)
-	if err != nil {
-		logger.Error("Failed to read delegation abi file, ", err)
-		return dumper, err
+	for _, name := range abiNames {
+		data, err := contractabi.Read(opts.ABIDir, name)
+		if err != nil {
+			logger.Error("Failed to read ", name, " abi file, ", err)
+			return dumper, err
+		}
+		contractABI, err := abi.JSON(strings.NewReader(string(data)))
+		if err != nil {
+			return dumper, err
+		}
+		dumper.contractABI = append(dumper.contractABI, contractABI)
 	}
-	delegationContractABI, err := abi.JSON(strings.NewReader(string(delegationABI)))
+	err = dumper.validateHandlers()
 	if err != nil {
 		return dumper, err
 	}
 
-	dumper.contractABI = []abi.ABI{licenseContractABI, delMemoContractABI, settlementContractABI, delegationContractABI}
-
 	for i := 0; i < len(dumper.contractABI); i++ {
 		for name, event := range dumper.contractABI[i].Events {
 			dumper.eventNameMap[event.ID] = name
-
-			var indexed abi.Arguments
-			for _, arg := range dumper.contractABI[i].Events[name].Inputs {
-				if arg.Indexed {
-					indexed = append(indexed, arg)
-				}
-			}
-			dumper.indexedMap[event.ID] = indexed
 		}
 	}
 
@@ -143,23 +186,109 @@ func NewDumper(ethrpc string, addrs *ContractAddress) (dumper *Dumper, err error
 	if err != nil {
 		blockNumber = 0
 	}
+	// logs of blocks before this one were dumped without being archived
+	_, err = database.GetArchiveStartBlock()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = database.SetArchiveStartBlock(blockNumber)
+		if err != nil {
+			return dumper, err
+		}
+	}
 	dumper.blockNumber = big.NewInt(blockNumber)
+	dumper.indexedBlock.Store(blockNumber - 1)
+
+	err = dumper.seedBalances()
+	if err != nil {
+		logger.Error("Failed to seed the DelMEMO balances, ", err)
+		return dumper, err
+	}
 
 	return dumper, nil
 }
 
+// Close releases the rpc connection of the dumper
+func (d *Dumper) Close() {
+	d.client.Close()
+}
+
+// SubscribeEvents keeps the database up to date with the chain. With a websocket endpoint
+// every new head triggers a dump, otherwise and whenever the subscription is down the
+// chain is polled. A dump always continues from the cursor, so gaps are backfilled.
 func (d *Dumper) SubscribeEvents(ctx context.Context) error {
+	wsRetryAt := time.Now()
 	for {
+		if d.wsEndpoint != "" && !time.Now().Before(wsRetryAt) {
+			err := d.subscribeHeads(ctx)
+			if ctx.Err() != nil {
+				return nil
+			}
+			logger.Warnf("head subscription dropped, poll every %s: %s", POLL_INTERVAL, err)
+			wsRetryAt = time.Now().Add(WS_RETRY_INTERVAL)
+		}
+
 		d.Dump()
 
 		select {
 		case <-ctx.Done():
 			return nil
-		case <-time.After(10 * time.Second):
+		case <-time.After(POLL_INTERVAL):
+		}
+	}
+}
+
+// subscribeHeads dumps on every new head pushed by the websocket endpoint, it returns
+// when the subscription fails
+func (d *Dumper) subscribeHeads(ctx context.Context) error {
+	dialCtx, cancel := context.WithTimeout(ctx, d.client.timeout)
+	defer cancel()
+	client, err := ethclient.DialContext(dialCtx, d.wsEndpoint)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	heads := make(chan *types.Header, 16)
+	sub, err := client.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+	logger.Info("subscribed to new heads")
+
+	// catch up with the blocks produced while not subscribed
+	d.Dump()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			return err
+		case <-time.After(WS_RETRY_INTERVAL):
+			return errors.New("no new head for " + WS_RETRY_INTERVAL.String())
+		case <-heads:
+			// drop the heads that queued up during the dump, one dump covers them all
+			for len(heads) > 0 {
+				<-heads
+			}
+			d.Dump()
 		}
 	}
 }
 
+// CheckRPCHealth checks the rpc endpoints periodically, so calls move back to a preferred
+// endpoint once it has recovered
+func (d *Dumper) CheckRPCHealth(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(RPC_HEALTH_INTERVAL):
+		}
+
+		d.client.CheckHealth()
+	}
+}
+
 func (d *Dumper) SubscribeEthPrice(ctx context.Context, apikey string) error {
 	URL = "https://api.etherscan.io/api?module=stats&action=ethprice&apikey=" + apikey
 	log.Println("Etherscan api url:", URL)
@@ -205,265 +334,391 @@ func GetEthPriceFromEtherscan() error {
 		logger.Error(err.Error())
 		return err
 	}
+	EthUSD_RefreshTime = time.Now().Unix()
+	metrics.EthUSD.Set(EthUSD)
 
 	return nil
 }
 
 func (d *Dumper) Dump() error {
-	client, err := ethclient.DialContext(context.TODO(), d.endpoint)
-	if err != nil {
-		logger.Error(err.Error())
-		return err
-	}
-	defer client.Close()
+	client := d.client
 
-	currentBlockNumber, err := client.BlockNumber(context.TODO())
+	currentBlockNumber, err := client.BlockNumber()
 	if err != nil {
 		logger.Error("BlockNumber err: ", err.Error())
 		return err
 	}
-	toBlock := big.NewInt(int64(currentBlockNumber - 1))
-
-	eventsLicenseNFT, err := client.FilterLogs(context.TODO(), ethereum.FilterQuery{
-		FromBlock: d.blockNumber,
-		ToBlock:   toBlock,
-		Addresses: []common.Address{d.contractAddress[0]},
-	})
+	d.chainHead.Store(currentBlockNumber)
+	toBlock, err := d.getToBlock(client, currentBlockNumber)
 	if err != nil {
-		logger.Error(err.Error())
+		logger.Error("get to block err: ", err.Error())
 		return err
 	}
-	eventsDelMEMO, err := client.FilterLogs(context.TODO(), ethereum.FilterQuery{
-		FromBlock: d.blockNumber,
-		ToBlock:   toBlock,
-		Addresses: []common.Address{d.contractAddress[1]},
-	})
+
+	// due failed events move the cursor back to their block, the ranges below replay them
+	err = d.retryFailedEvents()
 	if err != nil {
-		logger.Error(err.Error())
+		logger.Error("retry failed events err: ", err.Error())
 		return err
 	}
-	eventsSettlement, err := client.FilterLogs(context.TODO(), ethereum.FilterQuery{
-		FromBlock: d.blockNumber,
-		ToBlock:   toBlock,
-		Addresses: []common.Address{d.contractAddress[2]},
-	})
+
+	for {
+		// every range has to continue the chain dumped so far, a reorg since the last range
+		// moves the cursor back to the common ancestor first
+		err = d.checkReorg(client)
+		if err != nil {
+			logger.Error("check reorg err: ", err.Error())
+			return err
+		}
+		if toBlock.Cmp(d.blockNumber) < 0 {
+			break
+		}
+
+		chunkEnd := new(big.Int).Add(d.blockNumber, new(big.Int).SetUint64(d.chunkSize-1))
+		if chunkEnd.Cmp(toBlock) > 0 {
+			chunkEnd = toBlock
+		}
+
+		eventAmount, err := d.dumpRange(client, d.blockNumber, chunkEnd)
+		if err != nil {
+			if isRangeError(err) && d.chunkSize > 1 {
+				d.chunkSize = d.chunkSize / 2
+				logger.Warnf("shrink log range to %d blocks: %s", d.chunkSize, err)
+				continue
+			}
+			logger.Errorf("dump blocks %s-%s err: %s", d.blockNumber, chunkEnd, err)
+			return err
+		}
+
+		metrics.IndexLag.Set(float64(max(int64(currentBlockNumber)-d.IndexedBlock(), 0)))
+
+		// grow the window again when the range was sparse
+		if eventAmount < SPARSE_EVENT_AMOUNT && d.chunkSize < d.maxChunkSize {
+			d.chunkSize = min(d.chunkSize*2, d.maxChunkSize)
+		}
+
+		if toBlock.Cmp(d.blockNumber) >= 0 && d.chunkInterval > 0 {
+			time.Sleep(d.chunkInterval)
+		}
+	}
+
+	d.lastDump.Store(time.Now().Unix())
+	metrics.IndexLag.Set(float64(max(int64(currentBlockNumber)-d.IndexedBlock(), 0)))
+	return nil
+}
+
+// dumpRange handles the events from fromBlock to toBlock and moves the block number cursor
+// behind toBlock, it returns how many events were found
+func (d *Dumper) dumpRange(client *Client, fromBlock *big.Int, toBlock *big.Int) (int, error) {
+	logger.Debugf("dump blocks %s-%s", fromBlock, toBlock)
+
+	// the hash of toBlock is taken before the logs, so a reorg in between leaves a stale hash
+	// that the next reorg check catches, instead of a new hash over logs of the orphaned chain
+	header, err := client.HeaderByNumber(toBlock)
 	if err != nil {
-		logger.Error(err.Error())
-		return err
+		return 0, err
 	}
-	eventsDelegation, err := client.FilterLogs(context.TODO(), ethereum.FilterQuery{
-		FromBlock: d.blockNumber,
+
+	events, err := client.FilterLogs(ethereum.FilterQuery{
+		FromBlock: fromBlock,
 		ToBlock:   toBlock,
-		Addresses: []common.Address{d.contractAddress[3]},
+		Addresses: d.contractAddress,
 	})
 	if err != nil {
-		logger.Error(err.Error())
-		return err
+		return 0, err
 	}
-
-	for _, event := range eventsLicenseNFT {
-		err = nil
-		eventName, ok1 := d.eventNameMap[event.Topics[0]]
-		if !ok1 {
-			continue
-		}
-		switch eventName {
-		case "Transfer":
-			logger.Info("Handle LicenseNFT Mint event")
-			err = d.HandleLicenseMint(event)
-		default:
-			continue
-		}
-		if err != nil {
-			logger.Error(err.Error())
-			continue
+	for _, event := range events {
+		if event.BlockNumber == toBlock.Uint64() && event.BlockHash != header.Hash() {
+			return 0, fmt.Errorf("block %s was reorganized while its logs were fetched", toBlock)
 		}
 	}
 
-	for _, event := range eventsDelMEMO {
-		err = nil
-		eventName, ok1 := d.eventNameMap[event.Topics[0]]
-		if !ok1 {
-			continue
+	// handle events of all contracts in the order they happened on chain
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].BlockNumber != events[j].BlockNumber {
+			return events[i].BlockNumber < events[j].BlockNumber
 		}
-		switch eventName {
-		case "Transfer":
-			logger.Info("Handle DelMEMO transfer event")
-			err = d.HandleDelMemoTransfer(event)
-		case "Mint":
-			logger.Info("Handle DelMEMO Mint event")
-			err = d.HandleDelMemoMint(event)
-		case "Redeem":
-			logger.Info("Handle DelMEMO Redeem event")
-			blockTime, errGetBlock := safeGetBlockTime(client, event.BlockNumber)
-			if errGetBlock != nil {
-				logger.Error(err)
-				continue
-			}
-			err = d.HandleDelMemoRedeem(event, blockTime)
-		case "CancelRedeem":
-			logger.Info("Handle DelMEMO CancelRedeem event")
-			err = d.HandleDelMemoCancelRedeem(event)
-		case "Claim":
-			logger.Info("Handle DelMEMO Claim event")
-			err = d.HandleDelMemoClaim(event)
-		default:
-			continue
+		if events[i].TxIndex != events[j].TxIndex {
+			return events[i].TxIndex < events[j].TxIndex
+		}
+		return events[i].Index < events[j].Index
+	})
+	blockNumbers := make([]uint64, 0, len(events))
+	for _, event := range events {
+		blockNumbers = append(blockNumbers, event.BlockNumber)
+	}
+	blockTimes, err := d.blockTimes(blockNumbers)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < len(events); {
+		j := i + 1
+		for j < len(events) && events[j].BlockNumber == events[i].BlockNumber {
+			j++
 		}
+		err = d.applyBlock(events[i:j], blockTimes[events[i].BlockNumber], true)
 		if err != nil {
-			logger.Error(err.Error())
-			continue
+			return 0, err
 		}
+		i = j
 	}
 
-	for _, event := range eventsSettlement {
-		err = nil
-		eventName, ok1 := d.eventNameMap[event.Topics[0]]
-		if !ok1 {
-			continue
+	err = database.GlobalDataBase.Transaction(func(tx *gorm.DB) error {
+		info := database.BlockInfo{
+			BlockNumber: toBlock.Int64(),
+			BlockHash:   header.Hash().Hex(),
+			ParentHash:  header.ParentHash.Hex(),
 		}
-		switch eventName {
-		case "RewardWithdraw":
-			logger.Info("Handle Settlement RewardWithdraw event")
-			err = d.HandleSettlementRewardWithdraw(event)
-		case "FoundationWithdraw":
-			logger.Info("Handle Settlement FoundationWithdraw event")
-			err = d.HandleSettlementFoundationWithdraw(event)
-		default:
-			continue
+		err := info.CreateBlockInfo(tx)
+		if err != nil {
+			return err
 		}
+		err = database.PruneBlocks(tx, toBlock.Int64()-MAX_REORG_DEPTH)
 		if err != nil {
-			logger.Error(err.Error())
-			continue
+			return err
 		}
+		return database.SetBlockNumber(tx, toBlock.Int64()+1)
+	})
+	if err != nil {
+		return 0, err
 	}
+	d.blockNumber = new(big.Int).Add(toBlock, big.NewInt(1))
+	d.indexedBlock.Store(toBlock.Int64())
+	d.indexedTime.Store(header.Time)
 
-	for _, event := range eventsDelegation {
-		err = nil
-		eventName, ok1 := d.eventNameMap[event.Topics[0]]
-		if !ok1 {
-			continue
-		}
-		switch eventName {
-		case "NodeRegister":
-			logger.Info("Handle Delegation NodeRegister event")
-			blockTime, errGetBlock := safeGetBlockTime(client, event.BlockNumber)
-			if errGetBlock != nil {
-				logger.Error(err)
-				continue
+	return len(events), nil
+}
+
+// applyBlock runs the handlers of the events of one block in a single transaction together
+// with the block number cursor, so a block is either fully indexed or not at all.
+// An event whose handler fails is queued for retry, or aborts the block when halting on failure.
+// archive tells whether the raw logs still need to be written to the archive.
+// The chain state the handlers need is fetched before the transaction is opened.
+func (d *Dumper) applyBlock(events []types.Log, blockTime uint64, archive bool) error {
+	blockNumber := int64(events[0].BlockNumber)
+	fetched, err := d.fetchEvents(events)
+	if err != nil {
+		return err
+	}
+	err = database.GlobalDataBase.Transaction(func(tx *gorm.DB) error {
+		for i, event := range events {
+			if archive {
+				err := d.archiveEvent(tx, event, blockTime)
+				if err != nil {
+					return err
+				}
 			}
-			nodeInfo, err := d.getNodeInfo(client, event)
+
+			processed, err := database.IsEventProcessed(tx, event.TxHash.Hex(), event.Index)
 			if err != nil {
-				logger.Error(err.Error())
+				return err
+			}
+			if processed {
+				logger.Debugf("skip processed event, tx %s log %d", event.TxHash.Hex(), event.Index)
 				continue
 			}
-			err = d.HandleNodeRegister(event, blockTime, &nodeInfo)
+
+			if d.haltOnFailure {
+				err = d.handleEvent(tx, event, blockTime, fetched[i])
+				if err != nil {
+					return fmt.Errorf("handle event %s of tx %s: %w", d.eventNameMap[event.Topics[0]], event.TxHash.Hex(), err)
+				}
+			} else {
+				handled, err := d.tryHandleEvent(tx, event, blockTime, fetched[i])
+				if err != nil {
+					return err
+				}
+				if !handled {
+					continue
+				}
+			}
+
+			processedEvent := database.ProcessedEvent{
+				TxHash:      event.TxHash.Hex(),
+				LogIndex:    event.Index,
+				BlockNumber: blockNumber,
+			}
+			err = processedEvent.CreateProcessedEvent(tx)
 			if err != nil {
-				logger.Error(err.Error())
-				continue
+				return err
 			}
-		default:
-			continue
 		}
-	}
 
-	for _, event := range eventsDelegation {
-		err = nil
-		eventName, ok1 := d.eventNameMap[event.Topics[0]]
-		if !ok1 {
-			continue
+		info := database.BlockInfo{
+			BlockNumber: blockNumber,
+			BlockHash:   events[0].BlockHash.Hex(),
 		}
-		switch eventName {
-		case "ModifyCommissionRate":
-			logger.Info("Handle Delegation ModifyCommissionRate event")
-			blockTime, errGetBlock := safeGetBlockTime(client, event.BlockNumber)
-			if errGetBlock != nil {
-				logger.Error(err)
-				continue
-			}
-			err = d.HandleModifyCommissionRate(event, blockTime)
-		case "NodeWithdraw":
-			logger.Info("Handle Delegation NodeWithdraw event")
-			err = d.HandleNodeWithdraw(event)
-		case "ConfirmNodeReward":
-			logger.Info("Handle Delegation ConfirmNodeReward event")
-			err = d.HandleConfirmNodeReward(event)
-		case "NodeDailyDelegations":
-			logger.Info("Handle Delegation NodeDailyDelegations event")
-			err = d.HandleNodeDailyDelegations(event)
-		case "Delegate":
-			logger.Info("Handle Delegation Delegate event")
-			err = d.HandleDelegate(event)
-		case "Undelegate":
-			logger.Info("Handle Delegation Undelegate event")
-			err = d.HandleUndelegate(event)
-		case "Redelegate":
-			logger.Info("Handle Delegation Redelegate event")
-			err = d.HandleRedelegate(event)
-		case "ClaimReward":
-			logger.Info("Handle Delegation ClaimReward event")
-			err = d.HandleClaimReward(event)
-		default:
-			continue
+		err := info.CreateBlockInfo(tx)
+		if err != nil {
+			return err
 		}
+		return database.SetBlockNumber(tx, blockNumber+1)
+	})
+	if err != nil {
+		return err
+	}
+	d.blockNumber = big.NewInt(blockNumber + 1)
+	d.indexedBlock.Store(blockNumber)
+	d.indexedTime.Store(blockTime)
+	return nil
+}
+
+// getToBlock returns the newest block that is stable enough to be dumped
+func (d *Dumper) getToBlock(client *Client, currentBlockNumber uint64) (*big.Int, error) {
+	switch d.blockTag {
+	case "safe", "finalized":
+		tag := rpc.SafeBlockNumber
+		if d.blockTag == "finalized" {
+			tag = rpc.FinalizedBlockNumber
+		}
+		header, err := client.HeaderByNumber(big.NewInt(tag.Int64()))
 		if err != nil {
-			logger.Error(err.Error())
-			continue
+			return nil, err
 		}
+		return header.Number, nil
+	default:
+		return big.NewInt(int64(currentBlockNumber) - int64(d.confirmations)), nil
+	}
+}
+
+// IndexedBlock returns the last block that has been dumped into the database
+func (d *Dumper) IndexedBlock() int64 {
+	return d.indexedBlock.Load()
+}
+
+// ChainHead returns the latest block number of the chain seen by the last dump
+func (d *Dumper) ChainHead() uint64 {
+	return d.chainHead.Load()
+}
+
+// IndexedTime returns the time of the last block that has been dumped, 0 before the first dump
+func (d *Dumper) IndexedTime() uint64 {
+	return d.indexedTime.Load()
+}
+
+// LastDump returns the unix time the last dump finished without error, 0 before that
+func (d *Dumper) LastDump() int64 {
+	return d.lastDump.Load()
+}
+
+// RPCEndpoints returns the health of the rpc endpoints
+func (d *Dumper) RPCEndpoints() []EndpointStatus {
+	return d.client.Endpoints()
+}
+
+// BlockTag returns which block the dumper follows, and its confirmation depth for "latest"
+func (d *Dumper) BlockTag() (string, uint64) {
+	return d.blockTag, d.confirmations
+}
+
+// checkReorg compares the parent hash of the block after the last processed block with its hash,
+// the last processed block is the one before the cursor unless the cursor was rolled back.
+// If they differ it walks back to the common ancestor and reverts all blocks after it.
+func (d *Dumper) checkReorg(client *Client) error {
+	last, err := database.GetLatestBlockInfo()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
 	}
 
-	if toBlock.Cmp(d.blockNumber) == 1 {
-		newBlockNumber := new(big.Int).Add(toBlock, big.NewInt(1))
-		d.blockNumber = newBlockNumber
-		err = database.SetBlockNumber(newBlockNumber.Int64())
+	// there is nothing to compare with until the next block is mined
+	header, err := client.HeaderByNumber(big.NewInt(last.BlockNumber + 1))
+	if errors.Is(err, ethereum.NotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if header.ParentHash.Hex() == last.BlockHash {
+		return nil
+	}
+	logger.Warnf("chain reorganization detected after block %d", last.BlockNumber)
+
+	infos, err := database.GetRecentBlockInfos(MAX_REORG_DEPTH)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		header, err := client.HeaderByNumber(big.NewInt(info.BlockNumber))
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
 		if err != nil {
-			logger.Error(err.Error())
+			return err
+		}
+		if header.Hash().Hex() == info.BlockHash {
+			return d.rollback(info.BlockNumber+1, true)
 		}
 	}
+	return errors.New("chain reorganization is deeper than the tracked blocks, reindex is required")
+}
 
+// rollback reverts all rows derived from blocks >= blockNumber and continues dumping from blockNumber
+func (d *Dumper) rollback(blockNumber int64, orphaned bool) error {
+	logger.Warnf("roll back to block %d", blockNumber)
+	err := database.RevertBlocks(blockNumber, orphaned)
+	if err != nil {
+		return err
+	}
+	if orphaned {
+		d.headers.purge()
+	}
+	d.blockNumber = big.NewInt(blockNumber)
+	d.indexedBlock.Store(blockNumber - 1)
 	return nil
 }
 
+// unpack decodes the data and the indexed topics of the log with the abi of its contract
 func (d *Dumper) unpack(log types.Log, contractIndex uint8, out interface{}) error {
-	eventName := d.eventNameMap[log.Topics[0]]
-	indexed := d.indexedMap[log.Topics[0]]
+	event, err := d.contractABI[contractIndex].EventByID(log.Topics[0])
+	if err != nil {
+		return err
+	}
 
-	err := d.contractABI[contractIndex].UnpackIntoInterface(out, eventName, log.Data)
+	err = d.contractABI[contractIndex].UnpackIntoInterface(out, event.Name, log.Data)
 	if err != nil {
 		return err
 	}
 
+	var indexed abi.Arguments
+	for _, arg := range event.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
 	return abi.ParseTopics(out, indexed, log.Topics[1:])
 }
 
-func (d *Dumper) unpackLicenseTransfer(log types.Log) (string, string, string) {
-	from := common.BytesToAddress(log.Topics[1].Bytes()).Hex()
-	to := common.BytesToAddress(log.Topics[2].Bytes()).Hex()
-	tokenID := new(big.Int).SetBytes(log.Topics[3].Bytes()).String()
-	logger.Debug("unpack License-Transfer, from:", from, " to:", to, " tokenID:", tokenID)
-	return from, to, tokenID
+func blockTag(event *Event) database.BlockTag {
+	return database.BlockTag{
+		BlockNumber: int64(event.Log.BlockNumber),
+		BlockHash:   event.Log.BlockHash.Hex(),
+		Timestamp:   int64(event.BlockTime),
+	}
 }
 
-func (d *Dumper) getNodeInfo(client *ethclient.Client, log types.Log) (database.NodeInfoOnChain, error) {
+func (d *Dumper) getNodeInfo(client *Client, log types.Log) (database.NodeInfoOnChain, error) {
 	var nodeInfo database.NodeInfoOnChain
 	node, err := d.GetNodeAddr(log)
 	if err != nil {
 		return nodeInfo, err
 	}
-	data, err := d.contractABI[3].Pack("getNodeInfo", node)
+	data, err := d.contractABI[DELEGATION].Pack("getNodeInfo", node)
 	if err != nil {
 		return nodeInfo, err
 	}
 	callMsg := ethereum.CallMsg{
-		To:   &(d.contractAddress[3]),
+		To:   &(d.contractAddress[DELEGATION]),
 		Data: data,
 	}
-	res, err := client.CallContract(context.Background(), callMsg, nil)
+	res, err := client.CallContract(callMsg, new(big.Int).SetUint64(log.BlockNumber))
 	if err != nil {
 		return nodeInfo, err
 	}
 
-	temp, err := d.contractABI[3].Unpack("getNodeInfo", res)
+	temp, err := d.contractABI[DELEGATION].Unpack("getNodeInfo", res)
 	if err != nil {
 		return nodeInfo, err
 	}
@@ -472,17 +727,3 @@ func (d *Dumper) getNodeInfo(client *ethclient.Client, log types.Log) (database.
 	logger.Info("node info:", nodeInfo)
 	return nodeInfo, nil
 }
-
-func safeGetBlockTime(client *ethclient.Client, blockNumber uint64) (uint64, error) {
-	logger.Info("get block time, blocknumber: ", blockNumber)
-	block, err := client.BlockByNumber(context.Background(), big.NewInt(int64(blockNumber)))
-	if err != nil {
-		logger.Errorf("error fetching block: %w", err)
-		return 0, err
-	}
-	if block == nil {
-		logger.Errorf("block %d is nil", blockNumber)
-		return 0, errors.New("block is nil")
-	}
-	return block.Time(), nil
-}
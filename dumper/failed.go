@@ -0,0 +1,110 @@
+package dumper
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Me-Nodeslist/database/database"
+	"github.com/ethereum/go-ethereum/core/types"
+	"gorm.io/gorm"
+)
+
+const EVENT_SAVEPOINT = "event"
+const FAILED_RETRY_BASE = 30 * time.Second // delay before the first retry of a failed event
+const FAILED_RETRY_MAX = time.Hour         // the upper limit the retry delay grows to
+
+// tryHandleEvent runs the handler of the event in a savepoint of the block transaction.
+// When the handler fails its changes are rolled back and the event is put into the
+// dead-letter queue, so the rest of the block is still indexed. An event that is queued
+// already records another attempt, and is resolved when its handler succeeds. It reports
+// whether the handler succeeded.
+func (d *Dumper) tryHandleEvent(tx *gorm.DB, log types.Log, blockTime uint64, fetched interface{}) (bool, error) {
+	err := tx.SavePoint(EVENT_SAVEPOINT).Error
+	if err != nil {
+		return false, err
+	}
+	eventName := d.eventNameMap[log.Topics[0]]
+	handleErr := d.handleEvent(tx, log, blockTime, fetched)
+	if handleErr == nil {
+		resolved, err := database.ResolveFailedEvent(tx, log.TxHash.Hex(), log.Index)
+		if resolved {
+			logger.Infof("retry event %s of tx %s succeeded", eventName, log.TxHash.Hex())
+		}
+		return err == nil, err
+	}
+	err = tx.RollbackTo(EVENT_SAVEPOINT).Error
+	if err != nil {
+		return false, err
+	}
+
+	info, err := database.GetFailedEvent(tx, log.TxHash.Hex(), log.Index)
+	if err == nil {
+		attempts := info.Attempts + 1
+		delay := retryDelay(attempts)
+		logger.Warnf("retry event %s of tx %s failed, retry in %s: %s", eventName, log.TxHash.Hex(), delay, handleErr)
+		return false, database.UpdateFailedEventRetry(tx, info.ID, handleErr.Error(), attempts, time.Now().Add(delay).Unix())
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	logger.Errorf("handle event %s of tx %s failed, retry in %s: %s", eventName, log.TxHash.Hex(), FAILED_RETRY_BASE, handleErr)
+	raw, err := json.Marshal(log)
+	if err != nil {
+		return false, err
+	}
+	info = database.FailedEvent{
+		Contract:    log.Address.Hex(),
+		EventName:   eventName,
+		BlockNumber: int64(log.BlockNumber),
+		TxHash:      log.TxHash.Hex(),
+		LogIndex:    log.Index,
+		Timestamp:   int64(blockTime),
+		Log:         string(raw),
+		Error:       handleErr.Error(),
+		Attempts:    1,
+		NextRetry:   time.Now().Add(FAILED_RETRY_BASE).Unix(),
+	}
+	return false, info.CreateFailedEvent(tx)
+}
+
+// retryFailedEvents retries the due events of the dead-letter queue by rolling back to the
+// block of the oldest one, the dump replays it together with all later events in chain order,
+// so a retried handler never works on rows that events after it have changed already.
+// The blocks from the oldest unresolved event on are not pruned, only an event queued before
+// its block could be kept is too old to be rolled back to and needs a reindex from its block.
+func (d *Dumper) retryFailedEvents() error {
+	now := time.Now()
+	infos, err := database.GetDueFailedEvents(now.Unix())
+	if err != nil || len(infos) == 0 {
+		return err
+	}
+	oldest, err := database.GetOldestBlockInfo()
+	tracked := err == nil
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	for _, info := range infos {
+		if tracked && info.BlockNumber >= oldest.BlockNumber {
+			logger.Infof("retry event %s of tx %s, replay from its block %d", info.EventName, info.TxHash, info.BlockNumber)
+			return d.rollback(info.BlockNumber, false)
+		}
+		logger.Warnf("event %s of tx %s is in block %d that is no longer tracked, reindex from the block to retry it", info.EventName, info.TxHash, info.BlockNumber)
+		err = database.UpdateFailedEventRetry(database.GlobalDataBase, info.ID, info.Error, info.Attempts, now.Add(FAILED_RETRY_MAX).Unix())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retryDelay returns how long to wait after the given number of failed attempts
+func retryDelay(attempts int) time.Duration {
+	delay := FAILED_RETRY_BASE
+	for i := 1; i < attempts && delay < FAILED_RETRY_MAX; i++ {
+		delay *= 2
+	}
+	return min(delay, FAILED_RETRY_MAX)
+}
@@ -0,0 +1,26 @@
+package dumper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, FAILED_RETRY_BASE},
+		{1, FAILED_RETRY_BASE},
+		{2, 2 * FAILED_RETRY_BASE},
+		{3, 4 * FAILED_RETRY_BASE},
+		{7, 64 * FAILED_RETRY_BASE},
+		{8, FAILED_RETRY_MAX},
+		{1000, FAILED_RETRY_MAX},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
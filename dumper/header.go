@@ -0,0 +1,130 @@
+package dumper
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/Me-Nodeslist/database/database"
+)
+
+const HEADER_CACHE_SIZE = 4096 // how many block times are kept in memory
+const HEADER_BATCH_SIZE = 100  // how many headers are fetched in one batch request
+
+// headerCache keeps the times of the recently used blocks in memory
+type headerCache struct {
+	lk    sync.Mutex
+	size  int
+	items map[uint64]*list.Element
+	order *list.List // most recently used first
+}
+
+type headerEntry struct {
+	number uint64
+	time   uint64
+}
+
+func newHeaderCache(size int) *headerCache {
+	return &headerCache{
+		size:  size,
+		items: make(map[uint64]*list.Element),
+		order: list.New(),
+	}
+}
+
+func (c *headerCache) get(number uint64) (uint64, bool) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	elem, ok := c.items[number]
+	if !ok {
+		return 0, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*headerEntry).time, true
+}
+
+func (c *headerCache) add(number uint64, time uint64) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	if elem, ok := c.items[number]; ok {
+		elem.Value.(*headerEntry).time = time
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[number] = c.order.PushFront(&headerEntry{number: number, time: time})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*headerEntry).number)
+	}
+}
+
+// purge forgets every block, used after a reorganization
+func (c *headerCache) purge() {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	c.items = make(map[uint64]*list.Element)
+	c.order.Init()
+}
+
+// blockTimes returns the time of every block. It looks in memory first, then in the
+// database, and fetches the rest from the rpc in batches, caching what it fetched.
+func (d *Dumper) blockTimes(numbers []uint64) (map[uint64]uint64, error) {
+	times := make(map[uint64]uint64, len(numbers))
+	var missing []int64
+	for _, number := range numbers {
+		if _, ok := times[number]; ok {
+			continue
+		}
+		time, ok := d.headers.get(number)
+		if ok {
+			times[number] = time
+			continue
+		}
+		times[number] = 0
+		missing = append(missing, int64(number))
+	}
+	if len(missing) == 0 {
+		return times, nil
+	}
+
+	stored, err := database.GetBlockHeaders(missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range stored {
+		times[uint64(header.BlockNumber)] = uint64(header.Timestamp)
+		d.headers.add(uint64(header.BlockNumber), uint64(header.Timestamp))
+	}
+	var fetch []uint64
+	for _, number := range missing {
+		if times[uint64(number)] == 0 {
+			fetch = append(fetch, uint64(number))
+		}
+	}
+
+	for start := 0; start < len(fetch); start += HEADER_BATCH_SIZE {
+		batch := fetch[start:min(start+HEADER_BATCH_SIZE, len(fetch))]
+		logger.Debugf("fetch %d block headers from block %d", len(batch), batch[0])
+		headers, err := d.client.HeadersByNumber(batch)
+		if err != nil {
+			return nil, err
+		}
+		infos := make([]database.BlockHeader, 0, len(headers))
+		for _, header := range headers {
+			number := header.Number.Uint64()
+			times[number] = header.Time
+			d.headers.add(number, header.Time)
+			infos = append(infos, database.BlockHeader{
+				BlockNumber: int64(number),
+				BlockHash:   header.Hash().Hex(),
+				ParentHash:  header.ParentHash.Hex(),
+				Timestamp:   int64(header.Time),
+			})
+		}
+		err = database.CreateBlockHeaders(infos)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return times, nil
+}
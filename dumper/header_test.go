@@ -0,0 +1,31 @@
+package dumper
+
+import "testing"
+
+func TestHeaderCache(t *testing.T) {
+	c := newHeaderCache(2)
+	c.add(1, 100)
+	c.add(2, 200)
+	if _, ok := c.get(1); !ok { // 1 is now the most recently used
+		t.Fatal("block 1 should be cached")
+	}
+	c.add(3, 300) // evicts 2
+	if _, ok := c.get(2); ok {
+		t.Error("block 2 should have been evicted")
+	}
+	for number, want := range map[uint64]uint64{1: 100, 3: 300} {
+		if got, ok := c.get(number); !ok || got != want {
+			t.Errorf("get(%d) = %d, %v, want %d, true", number, got, ok, want)
+		}
+	}
+
+	c.add(3, 301)
+	if got, _ := c.get(3); got != 301 {
+		t.Errorf("get(3) = %d after update, want 301", got)
+	}
+
+	c.purge()
+	if _, ok := c.get(1); ok {
+		t.Error("block 1 should be gone after purge")
+	}
+}
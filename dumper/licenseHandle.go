@@ -1,7 +1,6 @@
 package dumper
 
 import (
-	"context"
 	"errors"
 	"math/big"
 	"os"
@@ -10,13 +9,13 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"gorm.io/gorm"
 )
 
 type LicenseTransfer struct {
 	From    common.Address
 	To      common.Address
-	TokenID *big.Int
+	TokenId *big.Int // named after the abi argument tokenId
 }
 
 type MetaData struct {
@@ -25,52 +24,78 @@ type MetaData struct {
 	Tier  uint8
 }
 
-func (d *Dumper) HandleLicenseMint(log types.Log) error {
-	from, to, tokenID := d.unpackLicenseTransfer(log)
+func init() {
+	RegisterHandler(LICENSE_NFT, "Transfer", LicenseTransfer{}, (*Dumper).HandleLicenseTransfer)
+}
 
-	if from != common.BigToAddress(big.NewInt(0)).Hex() {
-		logger.Debug("License Transfer event: From is not address(0), From is ", from)
-		return nil
-	}
+func (d *Dumper) HandleLicenseTransfer(tx *gorm.DB, event *Event) error {
+	out := event.Args.(*LicenseTransfer)
+	from, to, tokenID := out.From.Hex(), out.To.Hex(), out.TokenId.String()
+	logger.Debug("License Transfer, from:", from, " to:", to, " tokenID:", tokenID)
+	zero := common.BigToAddress(big.NewInt(0)).Hex()
 
-	// store info to db
 	licenseInfo := database.LicenseInfo{
-		TokenID: tokenID,
-		Owner:   to,
+		TokenID:  tokenID,
+		Owner:    to,
+		BlockTag: blockTag(event),
+	}
+	var err error
+	switch {
+	case from == zero:
+		err = licenseInfo.CreateLicenseInfo(tx)
+	case to == zero:
+		logger.Debug("License burned, tokenID is ", tokenID)
+		licenseInfo.Burned = true
+		err = licenseInfo.UpdateLicenseBurned(tx)
+	default:
+		err = licenseInfo.UpdateLicenseOwner(tx)
 	}
-	return licenseInfo.CreateLicenseInfo()
+	if err != nil {
+		return err
+	}
+
+	history := database.LicenseOwnerHistory{
+		TokenID:  tokenID,
+		From:     from,
+		To:       to,
+		TxHash:   event.Log.TxHash.Hex(),
+		BlockTag: blockTag(event),
+	}
+	return history.CreateLicenseOwnerHistory(tx)
 }
 
 func (d *Dumper) PurchaseTxValid(txHash string, receiver string, shouldValue float64, amount int64) (bool, error) {
 	logger.Debug("txHash:", txHash, " receiver:", receiver)
 
-	client, err := ethclient.DialContext(context.TODO(), d.endpoint)
-	if err != nil {
-		logger.Error(err.Error())
-		return false, err
+	client := d.client
+
+	// every field the purchase is judged by comes from the same endpoints, so with a quorum
+	// no single endpoint can approve it
+	var tx *types.Transaction
+	var receipt *types.Receipt
+	var err error
+	if d.purchaseQuorum > 1 {
+		tx, receipt, err = client.QuorumTransaction(common.HexToHash(txHash), d.purchaseQuorum)
+	} else {
+		var isPending bool
+		tx, isPending, err = client.TransactionByHash(common.HexToHash(txHash))
+		if err == nil {
+			logger.Debug("tx is pending:", isPending)
+			receipt, err = client.TransactionReceipt(common.HexToHash(txHash))
+		}
 	}
-	defer client.Close()
-
-	tx, isPending, err := client.TransactionByHash(context.Background(), common.HexToHash(txHash))
 	if err != nil {
 		logger.Error(err)
 		return false, err
 	}
-	logger.Debug("tx is pending:", isPending)
 	logger.Debug("tx timestamp:", tx.Time().Format("2006-01-02 15:04:05"))
 
-	receipt, err := client.TransactionReceipt(context.Background(), common.HexToHash(txHash))
-	if err != nil {
-		logger.Error(err)
-		return false, err
-	}
-
 	if receipt.Status == types.ReceiptStatusFailed {
 		logger.Debug("tx receipt status is failed")
 		return false, errors.New("tx receipt status is failed")
 	}
 
-	if tx.To().Hex() != LICENSE_PAYMENT_RECEIVER {
+	if tx.To() == nil || tx.To().Hex() != LICENSE_PAYMENT_RECEIVER {
 		logger.Debug("tx 'to' is not our receiver")
 		return false, errors.New("tx 'to' is not our receiver")
 	}
@@ -105,12 +130,7 @@ func (d *Dumper) PurchaseTxValid(txHash string, receiver string, shouldValue flo
 }
 
 func (d *Dumper) MintNFT(receiver string, amount int64, price uint64) (string, error) {
-	client, err := ethclient.DialContext(context.TODO(), d.endpoint)
-	if err != nil {
-		logger.Error(err.Error())
-		return "", err
-	}
-	defer client.Close()
+	client := d.client
 
 	privateKey, err := crypto.HexToECDSA(os.Getenv("PRIVATE_KEY"))
 	if err != nil {
@@ -120,7 +140,7 @@ func (d *Dumper) MintNFT(receiver string, amount int64, price uint64) (string, e
 
 	fromAddress := crypto.PubkeyToAddress(privateKey.PublicKey)
 
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
+	nonce, err := client.PendingNonceAt(fromAddress)
 	if err != nil {
 		logger.Errorf("Get nonce of %s failed", fromAddress.Hex())
 		return "", err
@@ -130,18 +150,18 @@ func (d *Dumper) MintNFT(receiver string, amount int64, price uint64) (string, e
 	metaData := MetaData{
 		Price: price,
 	}
-	data, err := d.contractABI[0].Pack("mint", userAddr, big.NewInt(amount), metaData)
+	data, err := d.contractABI[LICENSE_NFT].Pack("mint", userAddr, big.NewInt(amount), metaData)
 	if err != nil {
 		logger.Error("Pack mint tx input failed")
 		return "", err
 	}
 
 	gasLimit := uint64(200000)
-	gasPrice, _ := client.SuggestGasPrice(context.Background())
+	gasPrice, _ := client.SuggestGasPrice()
 
-	tx := types.NewTransaction(nonce, d.contractAddress[0], nil, gasLimit, gasPrice, data)
+	tx := types.NewTransaction(nonce, d.contractAddress[LICENSE_NFT], nil, gasLimit, gasPrice, data)
 
-	chainID, err := client.NetworkID(context.Background())
+	chainID, err := client.NetworkID()
 	if err != nil {
 		logger.Error("Get chainID failed")
 		return "", err
@@ -153,7 +173,7 @@ func (d *Dumper) MintNFT(receiver string, amount int64, price uint64) (string, e
 		return "", err
 	}
 
-	err = client.SendTransaction(context.Background(), signedTx)
+	err = client.SendTransaction(signedTx)
 	if err != nil {
 		logger.Error("Send tx failed")
 		return "", err
@@ -0,0 +1,49 @@
+package dumper
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Me-Nodeslist/database/database"
+)
+
+const LOCK_RENEW_INTERVAL = database.DUMPER_LOCK_TIMEOUT / 3
+
+// LockDatabase makes this process the only one writing the derived tables, it fails while a
+// running server or a reindex holds the database. The lock is renewed in the background until
+// the returned unlock is called.
+func LockDatabase() (unlock func(), err error) {
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d", host, os.Getpid())
+	err = database.AcquireDumperLock(owner, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(LOCK_RENEW_INTERVAL):
+			}
+			err := database.RenewDumperLock(owner, time.Now().Unix())
+			if err != nil {
+				logger.Error("renew the database lock err: ", err.Error())
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		err := database.ReleaseDumperLock(owner)
+		if err != nil {
+			logger.Error("release the database lock err: ", err.Error())
+		}
+	}, nil
+}
@@ -0,0 +1,166 @@
+package dumper
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/Me-Nodeslist/database/metrics"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/core/types"
+	"gorm.io/gorm"
+)
+
+// contracts the dumper indexes, in the order of contractAddress
+const (
+	LICENSE_NFT uint8 = iota
+	DEL_MEMO
+	SETTLEMENT
+	DELEGATION
+)
+
+// contract names used in logs, indexed by the constants above
+var contractNames = []string{"licenseNFT", "delMEMO", "settlement", "delegation"}
+
+// abi file names of the contracts, indexed by the constants above
+var abiNames = []string{"LicenseNFT", "DelMEMO", "Settlement", "Delegation"}
+
+// Event is a contract event passed to its handler
+type Event struct {
+	Log       types.Log
+	BlockTime uint64
+	Args      interface{} // pointer to the decoded args struct the handler was registered with
+	Fetched   interface{} // chain state returned by the fetcher of the event, nil without one
+}
+
+// HandlerFunc applies an event to the database inside the transaction of its block
+type HandlerFunc func(d *Dumper, tx *gorm.DB, event *Event) error
+
+// FetchFunc reads chain state the handler of an event needs at the block of the event.
+// It runs before the transaction of the block is opened, so no rpc call holds the database.
+type FetchFunc func(d *Dumper, log types.Log) (interface{}, error)
+
+type eventHandler struct {
+	args   reflect.Type // struct type the event arguments are decoded into
+	fetch  FetchFunc
+	handle HandlerFunc
+}
+
+// handlers of every indexed event by contract and event name
+var eventHandlers = make(map[uint8]map[string]*eventHandler)
+
+// RegisterHandler makes the dumper handle the event of the contract. The arguments of the
+// event are decoded into a new value of the type of args, its fields are named after the
+// abi arguments. It is meant to be called from init and panics on a duplicate handler.
+func RegisterHandler(contract uint8, event string, args interface{}, handle HandlerFunc) {
+	if eventHandlers[contract] == nil {
+		eventHandlers[contract] = make(map[string]*eventHandler)
+	}
+	if _, ok := eventHandlers[contract][event]; ok {
+		panic(fmt.Sprintf("duplicate handler of %s %s event", contractNames[contract], event))
+	}
+	eventHandlers[contract][event] = &eventHandler{
+		args:   reflect.TypeOf(args),
+		handle: handle,
+	}
+}
+
+// RegisterFetcher makes the dumper fetch chain state for the event before its block is applied,
+// the handler finds it in Event.Fetched. It panics if the event has no handler yet.
+func RegisterFetcher(contract uint8, event string, fetch FetchFunc) {
+	handler, ok := eventHandlers[contract][event]
+	if !ok {
+		panic(fmt.Sprintf("fetcher of %s %s event without handler", contractNames[contract], event))
+	}
+	handler.fetch = fetch
+}
+
+// validateHandlers checks that every event with a handler exists in the abi of its contract,
+// and that each of its arguments can be decoded into a field of the args struct
+func (d *Dumper) validateHandlers() error {
+	for contract, handlers := range eventHandlers {
+		for name, handler := range handlers {
+			event, ok := d.contractABI[contract].Events[name]
+			if !ok {
+				return fmt.Errorf("%s abi has no %s event", abiNames[contract], name)
+			}
+			if handler.args == nil {
+				continue
+			}
+			for _, arg := range event.Inputs {
+				fieldName := abi.ToCamelCase(arg.Name)
+				field, ok := handler.args.FieldByName(fieldName)
+				if !ok {
+					return fmt.Errorf("%s %s event: %s has no field %s for argument %s", abiNames[contract], name, handler.args.Name(), fieldName, arg.Name)
+				}
+				if field.Type != arg.Type.GetType() {
+					return fmt.Errorf("%s %s event: field %s of %s is %s, argument %s is %s", abiNames[contract], name, fieldName, handler.args.Name(), field.Type, arg.Name, arg.Type)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// lookupHandler returns the contract, the name and the handler of the event,
+// the handler is nil if the event is not handled
+func (d *Dumper) lookupHandler(log types.Log) (uint8, string, *eventHandler) {
+	if len(log.Topics) == 0 {
+		return 0, "", nil
+	}
+	contractIndex, ok := d.contractIndex[log.Address]
+	if !ok {
+		return 0, "", nil
+	}
+	abiEvent, err := d.contractABI[contractIndex].EventByID(log.Topics[0])
+	if err != nil {
+		return 0, "", nil
+	}
+	return contractIndex, abiEvent.Name, eventHandlers[contractIndex][abiEvent.Name]
+}
+
+// fetchEvents runs the fetchers of the events, the result of an event is at its index
+func (d *Dumper) fetchEvents(logs []types.Log) ([]interface{}, error) {
+	fetched := make([]interface{}, len(logs))
+	for i, log := range logs {
+		_, name, handler := d.lookupHandler(log)
+		if handler == nil || handler.fetch == nil {
+			continue
+		}
+		res, err := handler.fetch(d, log)
+		if err != nil {
+			return nil, fmt.Errorf("fetch state for event %s of tx %s: %w", name, log.TxHash.Hex(), err)
+		}
+		fetched[i] = res
+	}
+	return fetched, nil
+}
+
+// handleEvent decodes the event and dispatches it to the handler registered for it,
+// events without a handler are ignored. fetched is what the fetcher of the event returned.
+func (d *Dumper) handleEvent(tx *gorm.DB, log types.Log, blockTime uint64, fetched interface{}) error {
+	contractIndex, name, handler := d.lookupHandler(log)
+	if handler == nil {
+		return nil
+	}
+
+	logger.Infof("Handle %s %s event", contractNames[contractIndex], name)
+	event := &Event{
+		Log:       log,
+		BlockTime: blockTime,
+		Fetched:   fetched,
+	}
+	var err error
+	if handler.args != nil {
+		event.Args = reflect.New(handler.args).Interface()
+		err = d.unpack(log, contractIndex, event.Args)
+	}
+	if err == nil {
+		err = handler.handle(d, tx, event)
+	}
+	if err != nil {
+		metrics.HandlerErrors.WithLabelValues(contractNames[contractIndex], name).Inc()
+		return err
+	}
+	metrics.EventsProcessed.WithLabelValues(contractNames[contractIndex], name).Inc()
+	return nil
+}
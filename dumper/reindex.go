@@ -0,0 +1,128 @@
+package dumper
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/Me-Nodeslist/database/database"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const REPLAY_BATCH_SIZE = 10000 // how many blocks of archived logs to load at once
+
+// Reindex rebuilds the derived tables from fromBlock up to the last dumped block.
+// Changes after fromBlock are rolled back if the block is still tracked for reorgs,
+// otherwise all derived tables are truncated and rebuilt from block 0.
+// Events are replayed from the local archive when it covers the range, or from the rpc.
+func (d *Dumper) Reindex(fromBlock int64, useArchive bool) error {
+	client := d.client
+
+	endBlock := d.blockNumber.Int64() - 1
+	if fromBlock > endBlock {
+		return errors.New("nothing has been dumped after the block")
+	}
+
+	startBlock, err := d.resetDerivedTables(fromBlock)
+	if err != nil {
+		return err
+	}
+
+	archiveStart, err := database.GetArchiveStartBlock()
+	if useArchive && err == nil && startBlock >= archiveStart {
+		logger.Infof("replay archived events of blocks %d-%d", startBlock, endBlock)
+		return d.replayArchive(client, startBlock, endBlock)
+	}
+
+	logger.Infof("replay events from block %d by rpc", startBlock)
+	return d.Dump()
+}
+
+// resetDerivedTables brings the derived tables back to the state before fromBlock,
+// it returns the block that events have to be replayed from
+func (d *Dumper) resetDerivedTables(fromBlock int64) (int64, error) {
+	oldest, err := database.GetOldestBlockInfo()
+	if err == nil && fromBlock >= oldest.BlockNumber {
+		return fromBlock, d.rollback(fromBlock, false)
+	}
+
+	logger.Warnf("block %d is no longer tracked, rebuild all derived tables from block 0", fromBlock)
+	err = database.TruncateDerivedTables()
+	if err != nil {
+		return 0, err
+	}
+	d.blockNumber = big.NewInt(0)
+	d.indexedBlock.Store(-1)
+	return 0, nil
+}
+
+// replayArchive runs the handlers on the archived logs from fromBlock to toBlock
+func (d *Dumper) replayArchive(client *Client, fromBlock int64, toBlock int64) error {
+	for from := fromBlock; from <= toBlock; from += REPLAY_BATCH_SIZE {
+		to := min(from+REPLAY_BATCH_SIZE-1, toBlock)
+		infos, err := database.GetEventLogsInRange(from, to)
+		if err != nil {
+			return err
+		}
+
+		events := make([]types.Log, 0, len(infos))
+		for _, info := range infos {
+			event, err := archivedLog(info)
+			if err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+
+		for i := 0; i < len(events); {
+			j := i + 1
+			for j < len(events) && events[j].BlockNumber == events[i].BlockNumber {
+				j++
+			}
+			err = d.applyBlock(events[i:j], uint64(infos[i].Timestamp), false)
+			if err != nil {
+				return err
+			}
+			i = j
+		}
+
+		err = database.PruneBlocks(database.GlobalDataBase, to-MAX_REORG_DEPTH)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := database.SetBlockNumber(database.GlobalDataBase, toBlock+1)
+	if err != nil {
+		return err
+	}
+	d.blockNumber = big.NewInt(toBlock + 1)
+	d.indexedBlock.Store(toBlock)
+	return nil
+}
+
+// archivedLog turns an archived log back into the log the rpc returned
+func archivedLog(info database.EventLog) (types.Log, error) {
+	data, err := hexutil.Decode(info.Data)
+	if err != nil {
+		return types.Log{}, err
+	}
+	var topics []common.Hash
+	if info.Topics != "" {
+		for _, topic := range strings.Split(info.Topics, ",") {
+			topics = append(topics, common.HexToHash(topic))
+		}
+	}
+	return types.Log{
+		Address:     common.HexToAddress(info.Contract),
+		Topics:      topics,
+		Data:        data,
+		BlockNumber: uint64(info.BlockNumber),
+		BlockHash:   common.HexToHash(info.BlockHash),
+		TxHash:      common.HexToHash(info.TxHash),
+		TxIndex:     info.TxIndex,
+		Index:       info.LogIndex,
+	}, nil
+}
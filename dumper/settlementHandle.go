@@ -5,7 +5,7 @@ import (
 
 	"github.com/Me-Nodeslist/database/database"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/core/types"
+	"gorm.io/gorm"
 )
 
 type RewardWithdrawEvent struct {
@@ -15,35 +15,38 @@ type RewardWithdrawEvent struct {
 
 type FoundationWithdrawEvent struct {
 	Foundation common.Address
-	Amount   *big.Int
+	Amount     *big.Int
 }
 
-func (d *Dumper) HandleSettlementRewardWithdraw(log types.Log) error {
-	var out RewardWithdrawEvent
-	err := d.unpack(log, 2, &out)
-	if err != nil {
-		return err
-	}
+func init() {
+	RegisterHandler(SETTLEMENT, "RewardWithdraw", RewardWithdrawEvent{}, (*Dumper).HandleSettlementRewardWithdraw)
+	RegisterHandler(SETTLEMENT, "FoundationWithdraw", FoundationWithdrawEvent{}, (*Dumper).HandleSettlementFoundationWithdraw)
+}
+
+func (d *Dumper) HandleSettlementRewardWithdraw(tx *gorm.DB, event *Event) error {
+	out := event.Args.(*RewardWithdrawEvent)
 
 	// store info to db
 	info := database.RewardWithdrawInfo{
-		Receiver:  out.Receiver.Hex(),
-		Amount:    out.Amount.String(),
+		Receiver: out.Receiver.Hex(),
+		Kind:     database.WITHDRAW_REWARD,
+		Amount:   out.Amount.String(),
+		TxHash:   event.Log.TxHash.Hex(),
+		BlockTag: blockTag(event),
 	}
-	return info.CreateRewardWithdrawInfo()
+	return info.CreateRewardWithdrawInfo(tx)
 }
 
-func (d *Dumper) HandleSettlementFoundationWithdraw(log types.Log) error {
-	var out FoundationWithdrawEvent
-	err := d.unpack(log, 2, &out)
-	if err != nil {
-		return err
-	}
+func (d *Dumper) HandleSettlementFoundationWithdraw(tx *gorm.DB, event *Event) error {
+	out := event.Args.(*FoundationWithdrawEvent)
 
 	// store info to db
 	info := database.RewardWithdrawInfo{
-		Receiver:  out.Foundation.Hex(),
-		Amount:    out.Amount.String(),
+		Receiver: out.Foundation.Hex(),
+		Kind:     database.WITHDRAW_FOUNDATION,
+		Amount:   out.Amount.String(),
+		TxHash:   event.Log.TxHash.Hex(),
+		BlockTag: blockTag(event),
 	}
-	return info.CreateRewardWithdrawInfo()
+	return info.CreateRewardWithdrawInfo(tx)
 }
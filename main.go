@@ -13,9 +13,13 @@ import (
 // @description This is a server API for NodeList program
 // @host localhost:8088
 // @BasePath /v1
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+// @description "Bearer " followed by the ADMIN_TOKEN of the server
 func main() {
-	local := make([]*cli.Command, 0, 2)
-	local = append(local, cmd.ServerRunCmd, cmd.VersionCmd)
+	local := make([]*cli.Command, 0, 5)
+	local = append(local, cmd.ServerRunCmd, cmd.ReindexCmd, cmd.FailedCmd, cmd.StatusCmd, cmd.VersionCmd)
 	app := cli.App{
 		Commands: local,
 		Flags: []cli.Flag{
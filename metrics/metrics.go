@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const NAMESPACE = "nodedelegation"
+
+var (
+	EventsProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Subsystem: "dumper",
+		Name:      "events_processed_total",
+		Help:      "Contract events applied to the database by their handlers.",
+	}, []string{"contract", "event"})
+
+	HandlerErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Subsystem: "dumper",
+		Name:      "handler_errors_total",
+		Help:      "Contract events whose handlers failed, retries included.",
+	}, []string{"contract", "event"})
+
+	IndexLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: NAMESPACE,
+		Subsystem: "dumper",
+		Name:      "index_lag_blocks",
+		Help:      "How many blocks the last indexed block is behind the chain head.",
+	})
+
+	BalanceMismatches = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Subsystem: "dumper",
+		Name:      "delmemo_balance_mismatches_total",
+		Help:      "DelMEMO balances corrected by the reconciliation with the contract or kept from going negative.",
+	})
+
+	RPCDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Subsystem: "rpc",
+		Name:      "call_duration_seconds",
+		Help:      "Latency of a single rpc call attempt.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "result"})
+
+	FilterLogsSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Subsystem: "rpc",
+		Name:      "filter_logs_size",
+		Help:      "How many logs a FilterLogs call returned.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+	})
+
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Duration of the database statements by operation and table.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation", "table"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the http requests by route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	PurchaseAttempts = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Subsystem: "license",
+		Name:      "purchase_attempts_total",
+		Help:      "License purchase requests received.",
+	})
+
+	PurchaseResults = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Subsystem: "license",
+		Name:      "purchase_results_total",
+		Help:      "Finished license purchases by result: success, invalid payment or error.",
+	}, []string{"result"})
+
+	EthUSD = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: NAMESPACE,
+		Name:      "eth_usd_price",
+		Help:      "The latest ETH/USD price from etherscan.",
+	})
+)
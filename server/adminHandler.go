@@ -0,0 +1,144 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Me-Nodeslist/database/database"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type FailedEvent struct {
+	ID          uint   `json:"id"`
+	Contract    string `json:"contract"`
+	EventName   string `json:"eventName"`
+	BlockNumber int64  `json:"blockNumber"`
+	TxHash      string `json:"txHash"`
+	LogIndex    uint   `json:"logIndex"`
+	Timestamp   int64  `json:"timestamp"`
+	Error       string `json:"error"`
+	Attempts    int    `json:"attempts"`
+	NextRetry   int64  `json:"nextRetry"`
+	Resolved    bool   `json:"resolved"`
+}
+
+type FailedEvents struct {
+	Infos []FailedEvent `json:"infos"`
+}
+
+// adminAuth rejects requests without the admin token in the Authorization header
+func adminAuth(token string) gin.HandlerFunc {
+	expected := []byte("Bearer " + token)
+	return func(c *gin.Context) {
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), expected) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "invalid admin token",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// @Summary Get the events whose handlers failed
+// @Description Query the dead-letter queue of the dumper, newest first, support paging. Unresolved events are retried with a growing delay.
+// @Tags Admin
+// @Security AdminToken
+// @Accept json
+// @Produce json
+// @Param resolved query bool false "list the events that succeeded on a retry instead of the unresolved ones (default false)"
+// @Param offset query int false "paging start index (default 0)"
+// @Param limit query int false "number of items to return per page(default 10)"
+// @Success 200 {object} FailedEvents "return failed event list successfully"
+// @Failure 400 {object} map[string]string "request parameter error"
+// @Failure 401 {object} map[string]string "missing or invalid admin token"
+// @Failure 500 {object} map[string]string "internal server error"
+// @Router /admin/event/failed [get]
+func GetFailedEvents() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		offsetStr := c.Query("offset")
+		limitStr := c.Query("limit")
+
+		resolved, err := strconv.ParseBool(c.DefaultQuery("resolved", "false"))
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		infos, err := database.GetFailedEvents(resolved, offset, limit)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"infos": infos,
+		})
+	}
+}
+
+// @Summary Retry a failed event
+// @Description Make an unresolved event of the dead-letter queue due, the next dump cycle rolls back to its block and replays the event together with the events of all later blocks in chain order.
+// @Tags Admin
+// @Security AdminToken
+// @Accept json
+// @Produce json
+// @Param id path int true "id of the failed event"
+// @Success 200 {object} map[string]bool "the event will be retried in the next dump cycle"
+// @Failure 400 {object} map[string]string "request parameter error"
+// @Failure 401 {object} map[string]string "missing or invalid admin token"
+// @Failure 404 {object} map[string]string "no unresolved event with the id"
+// @Failure 500 {object} map[string]string "internal server error"
+// @Router /admin/event/failed/{id}/retry [post]
+func RetryFailedEvent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		err = database.RetryFailedEventNow(uint(id))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "no unresolved failed event " + c.Param("id"),
+			})
+			return
+		}
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/Me-Nodeslist/database/dumper"
+	"github.com/gin-gonic/gin"
+)
+
+type ChainHeight struct {
+	IndexedBlock  int64  `json:"indexedBlock" example:"1000"`
+	ChainHead     uint64 `json:"chainHead" example:"1002"`
+	BlockTag      string `json:"blockTag" example:"latest"`
+	Confirmations uint64 `json:"confirmations" example:"1"`
+}
+
+// @Summary Get the indexed block height and the chain head
+// @Description Query the last block dumped into the database and the latest block of the chain, so clients know how fresh the data is
+// @Tags Chain
+// @Accept json
+// @Produce json
+// @Success 200 {object} ChainHeight "return the heights successfully"
+// @Router /chain/height [get]
+func GetChainHeight(d *dumper.Dumper) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		blockTag, confirmations := d.BlockTag()
+		c.JSON(http.StatusOK, gin.H{
+			"indexedBlock":  d.IndexedBlock(),
+			"chainHead":     d.ChainHead(),
+			"blockTag":      blockTag,
+			"confirmations": confirmations,
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Me-Nodeslist/database/database"
+	"github.com/gin-gonic/gin"
+)
+
+type DelMEMOHolder struct {
+	Address   string `json:"address"`
+	Balance   string `json:"balance" example:"1000000000000000000"`
+	Redeeming string `json:"redeeming" example:"0"`
+}
+
+type DelMEMOHolders struct {
+	Amount int64           `json:"amount" example:"100"`
+	Infos  []DelMEMOHolder `json:"infos"`
+}
+
+type DelMEMOSupply struct {
+	Minted          string `json:"minted" example:"1000000"`
+	Redeemed        string `json:"redeemed" example:"300000"`
+	Claimed         string `json:"claimed" example:"150000"`
+	Canceled        string `json:"canceled" example:"50000"`
+	CanceledRedeems int64  `json:"canceledRedeems" example:"3"`
+	Circulating     string `json:"circulating" example:"750000"`
+	ServiceFees     string `json:"serviceFees" example:"10000"`
+}
+
+// @Summary Get the DelMEMO holders
+// @Description Query the addresses holding DelMEMO, largest balance first, support paging. Redeeming is the amount burned by redeems that are neither canceled nor claimed. Amount is the number of all holders.
+// @Tags DelMEMO
+// @Accept json
+// @Produce json
+// @Param offset query int false "paging start index (default 0)"
+// @Param limit query int false "number of items to return per page(default 10)"
+// @Success 200 {object} DelMEMOHolders "return holder list successfully"
+// @Failure 400 {object} map[string]string "request parameter error"
+// @Failure 500 {object} map[string]string "internal server error"
+// @Router /delmemo/holders [get]
+func GetDelMEMOHolders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		offsetStr := c.Query("offset")
+		limitStr := c.Query("limit")
+
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		amount, err := database.GetDelMEMOHolderAmount()
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		infos, err := database.GetDelMEMOHolders(offset, limit)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		holders := make([]DelMEMOHolder, 0, len(infos))
+		for _, info := range infos {
+			holders = append(holders, DelMEMOHolder{
+				Address:   info.Address,
+				Balance:   info.Balance,
+				Redeeming: info.Redeeming,
+			})
+		}
+		c.JSON(http.StatusOK, DelMEMOHolders{
+			Amount: amount,
+			Infos:  holders,
+		})
+	}
+}
+
+// @Summary Get the DelMEMO supply
+// @Description Query the DelMEMO totals: MEMO deposited by mints, DelMEMO redeemed, MEMO claimed, DelMEMO of canceled redeems and their number, the circulating DelMEMO, and the service fees kept from claimed redeems
+// @Tags DelMEMO
+// @Accept json
+// @Produce json
+// @Success 200 {object} DelMEMOSupply "return the totals successfully"
+// @Failure 500 {object} map[string]string "internal server error"
+// @Router /delmemo/supply [get]
+func GetDelMEMOSupply() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info, err := database.GetDelMEMOSupply(database.GlobalDataBase)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, DelMEMOSupply{
+			Minted:          info.Minted,
+			Redeemed:        info.Redeemed,
+			Claimed:         info.Claimed,
+			Canceled:        info.Canceled,
+			CanceledRedeems: info.CanceledRedeems,
+			Circulating:     info.Circulating,
+			ServiceFees:     info.ServiceFees,
+		})
+	}
+}
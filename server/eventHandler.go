@@ -0,0 +1,101 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Me-Nodeslist/database/database"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+type EventLog struct {
+	Contract    string `json:"contract"`
+	EventName   string `json:"eventName"`
+	BlockNumber int64  `json:"blockNumber"`
+	TxHash      string `json:"txHash"`
+	LogIndex    uint   `json:"logIndex"`
+	Timestamp   int64  `json:"timestamp"`
+	Args        string `json:"args" example:"{\"from\":\"0x...\",\"to\":\"0x...\",\"value\":\"1000\"}"`
+}
+
+type EventLogs struct {
+	Infos []EventLog `json:"infos"`
+}
+
+// @Summary Get the on-chain events related to an address
+// @Description Query the archived contract events whose arguments contain the address, newest first, support paging
+// @Tags Event
+// @Accept json
+// @Produce json
+// @Param address path string true "an ethereum address with prefix '0x'"
+// @Param offset query int false "paging start index (default 0)"
+// @Param limit query int false "number of items to return per page(default 10)"
+// @Success 200 {object} EventLogs "return event list successfully"
+// @Failure 400 {object} map[string]string "request parameter error"
+// @Failure 500 {object} map[string]string "internal server error"
+// @Router /event/address/{address} [get]
+func GetEventLogsOfAddress() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		address := c.Param("address")
+		offsetStr := c.Query("offset")
+		limitStr := c.Query("limit")
+
+		addr := common.HexToAddress(address)
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		infos, err := database.GetEventLogsByAddress(addr, offset, limit)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"infos": infos,
+		})
+	}
+}
+
+// @Summary Get the on-chain events of a transaction
+// @Description Query the archived contract events emitted by the transaction
+// @Tags Event
+// @Accept json
+// @Produce json
+// @Param txHash path string true "transaction hash with prefix '0x'"
+// @Success 200 {object} EventLogs "return event list successfully"
+// @Failure 500 {object} map[string]string "internal server error"
+// @Router /event/tx/{txHash} [get]
+func GetEventLogsOfTx() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		txHash := common.HexToHash(c.Param("txHash"))
+
+		infos, err := database.GetEventLogsByTxHash(txHash.Hex())
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"infos": infos,
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/Me-Nodeslist/database/database"
 	"github.com/Me-Nodeslist/database/dumper"
+	"github.com/Me-Nodeslist/database/metrics"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 )
@@ -21,12 +22,39 @@ type LicenseInfo struct {
 	TotalReward      string `json:"totalReward"`
 	InitialReward    string `json:"initialReward"`
 	WithdrawedReward string `json:"withdrawedReward"`
+	Burned           bool   `json:"burned"`
 }
 
 type LicenseInfos struct {
 	Infos []LicenseInfo `json:"infos"`
 }
 
+type LicenseOwnerHistory struct {
+	TokenID     string `json:"tokenID"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	TxHash      string `json:"txHash"`
+	BlockNumber int64  `json:"blockNumber"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
+type LicenseOwnerHistories struct {
+	Infos []LicenseOwnerHistory `json:"infos"`
+}
+
+type LicenseDelegationHistory struct {
+	TokenID     string `json:"tokenID"`
+	FromNode    string `json:"fromNode"`
+	ToNode      string `json:"toNode"`
+	TxHash      string `json:"txHash"`
+	BlockNumber int64  `json:"blockNumber"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
+type LicenseDelegationHistories struct {
+	Infos []LicenseDelegationHistory `json:"infos"`
+}
+
 type LicensePrice struct {
 	Usdt string // xxxUSDT/1License
 	Eth  string // xxxETH/1License
@@ -159,6 +187,82 @@ func GetLicenseInfosOfOwner() gin.HandlerFunc {
 	}
 }
 
+// @Summary Get the ownership history of a license
+// @Description Query every transfer of the license from mint to burn, oldest first. 'from' is address(0) when minted and 'to' is address(0) when burned
+// @Tags License
+// @Accept json
+// @Produce json
+// @Param tokenID path string true "license token id"
+// @Success 200 {object} LicenseOwnerHistories "return ownership history successfully"
+// @Failure 500 {object} map[string]string "internal server error"
+// @Router /license/owner/history/{tokenID} [get]
+func GetLicenseOwnerHistory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenID := c.Param("tokenID")
+
+		infos, err := database.GetLicenseOwnerHistories(tokenID)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"infos": infos,
+		})
+	}
+}
+
+// @Summary Get the delegation history of a license in pages
+// @Description Query every delegation change of the license, oldest first. 'fromNode' is address(0) when delegated and 'toNode' is address(0) when undelegated, support paging
+// @Tags License
+// @Accept json
+// @Produce json
+// @Param tokenID path string true "license token id"
+// @Param offset query int false "paging start index (default 0)"
+// @Param limit query int false  "number of items to return per page(default 10)"
+// @Success 200 {object} LicenseDelegationHistories "return delegation history successfully"
+// @Failure 400 {object} map[string]string "request parameter error"
+// @Failure 500 {object} map[string]string "internal server error"
+// @Router /license/history/{tokenID} [get]
+func GetLicenseDelegationHistory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenID := c.Param("tokenID")
+		offsetStr := c.Query("offset")
+		limitStr := c.Query("limit")
+
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		infos, err := database.GetLicenseDelegationHistories(tokenID, offset, limit)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"infos": infos,
+		})
+	}
+}
+
 // @Summary Get license price
 // @Description Get license price, include how many USDT and how many ETH
 // @Tags License
@@ -205,8 +309,10 @@ func GetLicensePrice() gin.HandlerFunc {
 // @Router /license/purchase [post]
 func HandleLicensePurchase(d *dumper.Dumper) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		metrics.PurchaseAttempts.Inc()
 		var req MintRequest
 		if err := c.BindJSON(&req); err != nil {
+			metrics.PurchaseResults.WithLabelValues("invalid").Inc()
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 			return
 		}
@@ -217,6 +323,7 @@ func HandleLicensePurchase(d *dumper.Dumper) gin.HandlerFunc {
 
 		isValid, err := d.PurchaseTxValid(req.TxHash, req.Receiver, value, req.Amount)
 		if !isValid {
+			metrics.PurchaseResults.WithLabelValues("invalid").Inc()
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
@@ -232,12 +339,14 @@ func HandleLicensePurchase(d *dumper.Dumper) gin.HandlerFunc {
 		err = history.CreateLicensePurchaseHistory()
 		if err != nil {
 			logger.Debug(err)
+			metrics.PurchaseResults.WithLabelValues("error").Inc()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
 		txHash, err := d.MintNFT(req.Receiver, req.Amount, dumper.LICENSE_PRICE_USDT)
 		if err != nil {
+			metrics.PurchaseResults.WithLabelValues("error").Inc()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -246,10 +355,12 @@ func HandleLicensePurchase(d *dumper.Dumper) gin.HandlerFunc {
 		err = history.UpdateLicensePurchaseHistory()
 		if err != nil {
 			logger.Debug(err)
+			metrics.PurchaseResults.WithLabelValues("error").Inc()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
+		metrics.PurchaseResults.WithLabelValues("success").Inc()
 		c.JSON(http.StatusOK, gin.H{"success": true, "txHash": txHash})
 	}
 }
@@ -1,12 +1,16 @@
 package server
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Me-Nodeslist/database/database"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type NodeInfo struct {
@@ -24,12 +28,48 @@ type NodeInfo struct {
 	OnlineDays                 int64 `json:"onlineDays"`
 	OnlineDays_RecentMonth     int64 `json:"onlineDays_RecentMonth"`
 	OnlineDays_RecentWeek      int64 `json:"onlineDays_RecentWeek"`
+	CommissionRateChanged      bool  `json:"commissionRateChanged"`
 }
 
 type NodeInfos struct {
 	Infos []NodeInfo `json:"infos"`
 }
 
+type CommissionRateHistory struct {
+	NodeAddress string `json:"nodeAddress"`
+	OldRate     uint8  `json:"oldRate"`
+	NewRate     uint8  `json:"newRate"`
+	TxHash      string `json:"txHash"`
+	BlockNumber int64  `json:"blockNumber"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
+type CommissionRateHistories struct {
+	Infos []CommissionRateHistory `json:"infos"`
+}
+
+type NodeDailyDelegation struct {
+	Date             uint16 `json:"date"`
+	DelegationAmount uint16 `json:"delegationAmount"`
+}
+
+type NodeDetail struct {
+	Info             NodeInfo              `json:"info"`
+	LicenseAmount    int64                 `json:"licenseAmount" example:"100"`
+	Licenses         []LicenseInfo         `json:"licenses"`
+	DelegatorAmount  int64                 `json:"delegatorAmount" example:"20"`
+	DailyDelegations []NodeDailyDelegation `json:"dailyDelegations"`
+}
+
+// nodeInfoWithRateFlag is a node with whether it changed its commission rate recently
+type nodeInfoWithRateFlag struct {
+	database.NodeInfo
+	CommissionRateChanged bool
+}
+
+const DEFAULT_RATE_CHANGE_DAYS = 30
+const DEFAULT_DAILY_DELEGATION_DAYS = 30
+
 // @Summary Get the amount of all registered nodes
 // @Description Query the amount of the registered nodes in nodelist server
 // @Tags Node
@@ -61,6 +101,7 @@ func GetNodeAmount() gin.HandlerFunc {
 // @Produce json
 // @Param offset query int false "paging start index (default 0)"
 // @Param limit query int false "number of items to return per page(default 10)"
+// @Param days query int false "flag the nodes that changed their commission rate in the recent days(default 30)"
 // @Success 200 {object} NodeInfos "return node info list successfully"
 // @Failure 400 {object} map[string]string "request parameter error"
 // @Failure 500 {object} map[string]string "internal server error"
@@ -69,6 +110,7 @@ func GetNodeInfos() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		offsetStr := c.Query("offset")
 		limitStr := c.Query("limit")
+		daysStr := c.DefaultQuery("days", strconv.Itoa(DEFAULT_RATE_CHANGE_DAYS))
 
 		offset, err := strconv.Atoi(offsetStr)
 		if err != nil {
@@ -87,6 +129,15 @@ func GetNodeInfos() gin.HandlerFunc {
 			return
 		}
 
+		days, err := strconv.Atoi(daysStr)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
 		infos, err := database.GetNodeInfos(offset, limit)
 		if err != nil {
 			logger.Error(err.Error())
@@ -95,6 +146,73 @@ func GetNodeInfos() gin.HandlerFunc {
 			})
 			return
 		}
+		since := time.Now().Add(-time.Duration(days) * 24 * time.Hour).Unix()
+		changed, err := database.GetCommissionRateChangedNodes(since)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		flagged := make([]nodeInfoWithRateFlag, 0, len(infos))
+		for _, info := range infos {
+			flagged = append(flagged, nodeInfoWithRateFlag{
+				NodeInfo:              info,
+				CommissionRateChanged: changed[info.NodeAddress],
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"infos": flagged,
+		})
+	}
+}
+
+// @Summary Get the commission rate history of a node(paging)
+// @Description Query every commission rate change of the node, oldest first, support paging
+// @Tags Node
+// @Accept json
+// @Produce json
+// @Param address path string true "node address(an ethereum address with prefix '0x')"
+// @Param offset query int false "paging start index (default 0)"
+// @Param limit query int false "number of items to return per page(default 10)"
+// @Success 200 {object} CommissionRateHistories "return commission rate history successfully"
+// @Failure 400 {object} map[string]string "request parameter error"
+// @Failure 500 {object} map[string]string "internal server error"
+// @Router /node/commission/history/{address} [get]
+func GetNodeCommissionRateHistory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		address := c.Param("address")
+		node := common.HexToAddress(address)
+
+		offsetStr := c.Query("offset")
+		limitStr := c.Query("limit")
+
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		infos, err := database.GetCommissionRateHistories(node, offset, limit)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusOK, gin.H{
 			"infos": infos,
 		})
@@ -115,7 +233,7 @@ func GetNodeInfoOfOwner() gin.HandlerFunc {
 		address := c.Param("address")
 		owner := common.HexToAddress(address)
 
-		info, err := database.GetNodeInfoByNodeAddress(owner)
+		info, err := database.GetNodeInfoByNodeAddress(database.GlobalDataBase, owner)
 		if err != nil {
 			logger.Error(err.Error())
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -219,7 +337,7 @@ func GetNodeInfosOfdelegation() gin.HandlerFunc {
 		for i:=0;i<int(amount);i++{
 			if infos[i].Delegated {
 				delegatedNode := infos[i].DelegatedNode
-				nodeInfo, err := database.GetNodeInfoByNodeAddress(common.HexToAddress(delegatedNode))
+				nodeInfo, err := database.GetNodeInfoByNodeAddress(database.GlobalDataBase, common.HexToAddress(delegatedNode))
 				if err != nil {
 					logger.Error(err.Error())
 					c.JSON(http.StatusInternalServerError, gin.H{
@@ -235,3 +353,128 @@ func GetNodeInfosOfdelegation() gin.HandlerFunc {
 		})
 	}
 }
+
+// @Summary Get the detail of a node
+// @Description Query a node by its id or address: its information including the commission rate and rewards, the licenses delegated to it(support paging) and their amount, how many owners delegate to it, and its daily delegation amounts in the recent days, oldest first
+// @Tags Node
+// @Accept json
+// @Produce json
+// @Param idOrAddress path string true "node id, or node address(an ethereum address with prefix '0x')"
+// @Param offset query int false "paging start index of the licenses (default 0)"
+// @Param limit query int false "number of licenses to return per page(default 10)"
+// @Param days query int false "number of recent days of the daily delegation amounts(default 30)"
+// @Success 200 {object} NodeDetail "return node detail successfully"
+// @Failure 400 {object} map[string]string "request parameter error"
+// @Failure 404 {object} map[string]string "node not found"
+// @Failure 500 {object} map[string]string "internal server error"
+// @Router /node/{idOrAddress} [get]
+func GetNodeDetail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idOrAddress := c.Param("idOrAddress")
+		offsetStr := c.DefaultQuery("offset", "0")
+		limitStr := c.DefaultQuery("limit", "10")
+		daysStr := c.DefaultQuery("days", strconv.Itoa(DEFAULT_DAILY_DELEGATION_DAYS))
+
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		days, err := strconv.Atoi(daysStr)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		var info database.NodeInfo
+		if strings.HasPrefix(idOrAddress, "0x") {
+			if !common.IsHexAddress(idOrAddress) {
+				logger.Error("invalid node address ", idOrAddress)
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "invalid node address " + idOrAddress,
+				})
+				return
+			}
+			info, err = database.GetNodeInfoByNodeAddress(database.GlobalDataBase, common.HexToAddress(idOrAddress))
+		} else {
+			id, perr := strconv.ParseUint(idOrAddress, 10, 32)
+			if perr != nil {
+				logger.Error(perr.Error())
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": perr.Error(),
+				})
+				return
+			}
+			info, err = database.GetNodeInfoByNodeID(uint32(id))
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "no node " + idOrAddress,
+			})
+			return
+		}
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		node := common.HexToAddress(info.NodeAddress)
+
+		licenseAmount, err := database.GetLicenseAmountByNode(node)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		licenses, err := database.GetLicenseInfosByNode(database.GlobalDataBase, node, offset, limit)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		delegatorAmount, err := database.GetDelegatorAmountByNode(node)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		dailyDelegations, err := database.GetNodeDailyDelegations(node, days)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"info":             info,
+			"licenseAmount":    licenseAmount,
+			"licenses":         licenses,
+			"delegatorAmount":  delegatorAmount,
+			"dailyDelegations": dailyDelegations,
+		})
+	}
+}
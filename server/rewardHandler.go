@@ -3,6 +3,8 @@ package server
 import (
 	"math/big"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/Me-Nodeslist/database/database"
 	"github.com/ethereum/go-ethereum/common"
@@ -94,7 +96,7 @@ func GetRewardInfo() gin.HandlerFunc {
 		// get node rewards
 		nodeReward := "0"
 		withdrawedNodeReward := "0"
-		nodeInfo, err := database.GetNodeInfoByNodeAddress(owner)
+		nodeInfo, err := database.GetNodeInfoByNodeAddress(database.GlobalDataBase, owner)
 		if err != nil && err != gorm.ErrRecordNotFound {
 			logger.Error(err.Error())
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -117,6 +119,45 @@ func GetRewardInfo() gin.HandlerFunc {
 	}
 }
 
+type RedeemHistory struct {
+	RedeemID     string `json:"redeemID" example:"1"`
+	Initiator    string `json:"initiator"`
+	RedeemAmount string `json:"redeemAmount" example:"1000"`
+	ClaimAmount  string `json:"claimAmount" example:"950"`
+	LockDuration uint32 `json:"lockDuration" example:"604800"`
+	UnlockDate   int64  `json:"unlockDate" example:"1735689600"`
+	Status       string `json:"status" example:"locked"`
+}
+
+type RedeemHistories struct {
+	Infos []RedeemHistory `json:"infos"`
+}
+
+type RewardWithdrawHistory struct {
+	Receiver    string `json:"receiver"`
+	Kind        string `json:"kind" example:"reward"`
+	Amount      string `json:"amount" example:"1000"`
+	TxHash      string `json:"txHash"`
+	BlockNumber int64  `json:"blockNumber" example:"100"`
+	Timestamp   int64  `json:"timestamp" example:"1735689600"`
+}
+
+type RewardWithdrawHistories struct {
+	Infos []RewardWithdrawHistory `json:"infos"`
+}
+
+type RewardWithdrawTotal struct {
+	RewardAmount     string `json:"rewardAmount" example:"1000"`
+	FoundationAmount string `json:"foundationAmount" example:"0"`
+	TotalAmount      string `json:"totalAmount" example:"1000"`
+}
+
+// redeemInfoWithStatus is a redeem with its status at the time of the request
+type redeemInfoWithStatus struct {
+	database.RedeemInfo
+	Status string
+}
+
 // @Summary Get the account's redeem information
 // @Description Query all locked, unlocked, withdrawed MEMOs and redeeming DelMEMOs of specific owner, as well as unclaimed redeemIDs
 // @Tags Redeem
@@ -192,3 +233,181 @@ func GetRedeemInfo() gin.HandlerFunc {
 		})
 	}
 }
+
+// @Summary Get the account's redeem history
+// @Description Query the redeems of specific owner newest first, with their amounts, lock duration, unlock date and status, support paging. Status is one of locked, claimable, claimed and canceled, redeems in every status are returned if it is not given.
+// @Tags Redeem
+// @Accept json
+// @Produce json
+// @Param address path string true "owner address(an ethereum address with prefix '0x')"
+// @Param status query string false "only return redeems in this status" Enums(locked, claimable, claimed, canceled)
+// @Param offset query int false "paging start index (default 0)"
+// @Param limit query int false "number of items to return per page(default 10)"
+// @Success 200 {object} RedeemHistories "return redeem history successfully"
+// @Failure 400 {object} map[string]string "request parameter error"
+// @Failure 500 {object} map[string]string "internal server error"
+// @Router /reward/redeem/history/{address} [get]
+func GetRedeemHistory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		address := c.Param("address")
+		owner := common.HexToAddress(address)
+		status := c.Query("status")
+		offsetStr := c.Query("offset")
+		limitStr := c.Query("limit")
+
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		switch status {
+		case "", database.REDEEM_LOCKED, database.REDEEM_CLAIMABLE, database.REDEEM_CLAIMED, database.REDEEM_CANCELED:
+		default:
+			logger.Error("unknown redeem status ", status)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "unknown redeem status " + status,
+			})
+			return
+		}
+
+		now := time.Now().Unix()
+		infos, err := database.GetRedeemInfosByInitiator(owner, status, now, offset, limit)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		histories := make([]redeemInfoWithStatus, 0, len(infos))
+		for _, info := range infos {
+			histories = append(histories, redeemInfoWithStatus{
+				RedeemInfo: info,
+				Status:     info.Status(now),
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"infos": histories,
+		})
+	}
+}
+
+// @Summary Get the account's reward withdrawal history
+// @Description Query the withdrawals from the settlement contract to specific receiver newest first, support paging. Kind is reward for RewardWithdraw events and foundation for FoundationWithdraw events, withdrawals of both kinds are returned if it is not given. Timestamp is the block time.
+// @Tags Reward
+// @Accept json
+// @Produce json
+// @Param address path string true "receiver address(an ethereum address with prefix '0x')"
+// @Param kind query string false "only return withdrawals of this kind" Enums(reward, foundation)
+// @Param offset query int false "paging start index (default 0)"
+// @Param limit query int false "number of items to return per page(default 10)"
+// @Success 200 {object} RewardWithdrawHistories "return withdrawal history successfully"
+// @Failure 400 {object} map[string]string "request parameter error"
+// @Failure 500 {object} map[string]string "internal server error"
+// @Router /reward/withdraw/history/{address} [get]
+func GetRewardWithdrawHistory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		address := c.Param("address")
+		receiver := common.HexToAddress(address)
+		kind := c.Query("kind")
+		offsetStr := c.Query("offset")
+		limitStr := c.Query("limit")
+
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		switch kind {
+		case "", database.WITHDRAW_REWARD, database.WITHDRAW_FOUNDATION:
+		default:
+			logger.Error("unknown withdrawal kind ", kind)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "unknown withdrawal kind " + kind,
+			})
+			return
+		}
+
+		infos, err := database.GetWithdrawInfosByReceiver(receiver, kind, offset, limit)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"infos": infos,
+		})
+	}
+}
+
+// @Summary Get the account's reward withdrawal totals
+// @Description Query what specific receiver withdrew from the settlement contract by RewardWithdraw events, by FoundationWithdraw events and in total
+// @Tags Reward
+// @Accept json
+// @Produce json
+// @Param address path string true "receiver address(an ethereum address with prefix '0x')"
+// @Success 200 {object} RewardWithdrawTotal "return withdrawal totals successfully"
+// @Failure 500 {object} map[string]string "internal server error"
+// @Router /reward/withdraw/total/{address} [get]
+func GetRewardWithdrawTotal() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		address := c.Param("address")
+		receiver := common.HexToAddress(address)
+
+		rewardAmount, err := database.GetTotalWithdrawAmountByReceiver(receiver, database.WITHDRAW_REWARD)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		foundationAmount, err := database.GetTotalWithdrawAmountByReceiver(receiver, database.WITHDRAW_FOUNDATION)
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		// rows indexed before withdrawals had a kind count only in the total
+		totalAmount, err := database.GetTotalWithdrawAmountByReceiver(receiver, "")
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"rewardAmount":     rewardAmount.String(),
+			"foundationAmount": foundationAmount.String(),
+			"totalAmount":      totalAmount.String(),
+		})
+	}
+}
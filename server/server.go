@@ -3,12 +3,17 @@ package server
 import (
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/Me-Nodeslist/database/docs"
 	"github.com/Me-Nodeslist/database/dumper"
 	"github.com/Me-Nodeslist/database/logs"
+	"github.com/Me-Nodeslist/database/metrics"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
@@ -26,6 +31,7 @@ func NewServer(endpoint string, d *dumper.Dumper) (*http.Server, error) {
 
 	// allow all cross-origin requests
 	router.Use(cors.Default())
+	router.Use(observeRequest())
 
 	router.MaxMultipartMemory = 8 << 20 // 8 MiB
 	router.GET("/", func(c *gin.Context) {
@@ -34,6 +40,7 @@ func NewServer(endpoint string, d *dumper.Dumper) (*http.Server, error) {
 
 	docs.SwaggerInfo.BasePath = "/v1"
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	r := Router{
 		router,
@@ -41,6 +48,10 @@ func NewServer(endpoint string, d *dumper.Dumper) (*http.Server, error) {
 	r.registerLicenseRouter(d)
 	r.registerNodeRouter()
 	r.registerRewardRouter()
+	r.registerDelMEMORouter()
+	r.registerChainRouter(d)
+	r.registerEventRouter()
+	r.registerAdminRouter()
 
 	return &http.Server{
 		Addr:    endpoint,
@@ -48,10 +59,27 @@ func NewServer(endpoint string, d *dumper.Dumper) (*http.Server, error) {
 	}, nil
 }
 
+// observeRequest records the latency and status of every request by its route pattern,
+// requests that match no route share one label
+func observeRequest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Observe(time.Since(start).Seconds())
+	}
+}
+
 func (r Router) registerLicenseRouter(d *dumper.Dumper) {
 	r.GET("/license/amount", GetLicenseAmount()) // all and delegated
 	r.GET("/license/amount/owner/:address", GetLicenseAmountOfOwner())
 	r.GET("/license/info/owner/:address", GetLicenseInfosOfOwner()) // page
+	r.GET("/license/owner/history/:tokenID", GetLicenseOwnerHistory())
+	r.GET("/license/history/:tokenID", GetLicenseDelegationHistory()) // page
 	r.GET("/license/price", GetLicensePrice())
 	r.POST("/license/purchase", HandleLicensePurchase(d))
 }
@@ -60,11 +88,44 @@ func (r Router) registerNodeRouter() {
 	r.GET("/node/amount", GetNodeAmount())
 	r.GET("/node/info", GetNodeInfos()) // page
 	r.GET("/node/info/owner/:address", GetNodeInfoOfOwner())
-	r.GET("/node/info/recipient/:address", GetNodeInfosOfRecipient())   // page
-	r.GET("/node/info/delegation/:address", GetNodeInfosOfdelegation()) // page
+	r.GET("/node/info/recipient/:address", GetNodeInfosOfRecipient())          // page
+	r.GET("/node/info/delegation/:address", GetNodeInfosOfdelegation())        // page
+	r.GET("/node/commission/history/:address", GetNodeCommissionRateHistory()) // page
+	r.GET("/node/:idOrAddress", GetNodeDetail())                               // page
 }
 
 func (r Router) registerRewardRouter() {
 	r.GET("/reward/info/:address", GetRewardInfo())
 	r.GET("/reward/redeem/info/:address", GetRedeemInfo())
+	r.GET("/reward/redeem/history/:address", GetRedeemHistory())           // page
+	r.GET("/reward/withdraw/history/:address", GetRewardWithdrawHistory()) // page
+	r.GET("/reward/withdraw/total/:address", GetRewardWithdrawTotal())
+}
+
+func (r Router) registerDelMEMORouter() {
+	r.GET("/delmemo/holders", GetDelMEMOHolders()) // page
+	r.GET("/delmemo/supply", GetDelMEMOSupply())
+}
+
+func (r Router) registerChainRouter(d *dumper.Dumper) {
+	r.GET("/chain/height", GetChainHeight(d))
+	r.GET("/status", GetStatus(d))
+}
+
+func (r Router) registerEventRouter() {
+	r.GET("/event/address/:address", GetEventLogsOfAddress()) // page
+	r.GET("/event/tx/:txHash", GetEventLogsOfTx())
+}
+
+// registerAdminRouter serves the admin endpoints only when ADMIN_TOKEN is set,
+// and only to requests that carry it
+func (r Router) registerAdminRouter() {
+	token := os.Getenv("ADMIN_TOKEN")
+	if token == "" {
+		logger.Info("ADMIN_TOKEN is not set, admin endpoints are disabled")
+		return
+	}
+	admin := r.Group("/admin", adminAuth(token))
+	admin.GET("/event/failed", GetFailedEvents()) // page
+	admin.POST("/event/failed/:id/retry", RetryFailedEvent())
 }
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Me-Nodeslist/database/database"
+	"github.com/Me-Nodeslist/database/dumper"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RPCEndpoint struct {
+	URL     string `json:"url" example:"https://rpc.example.com"`
+	Healthy bool   `json:"healthy"`
+	Height  uint64 `json:"height" example:"1002"`
+	InUse   bool   `json:"inUse"`
+}
+
+type Status struct {
+	IndexedBlock      int64         `json:"indexedBlock" example:"1000"`
+	ChainHead         uint64        `json:"chainHead" example:"1002"`
+	LagBlocks         int64         `json:"lagBlocks" example:"2"`
+	LagSeconds        int64         `json:"lagSeconds" example:"24"`
+	LastDump          int64         `json:"lastDump" example:"1700000000"`
+	FailedEvents      int64         `json:"failedEvents" example:"0"`
+	RPCEndpoints      []RPCEndpoint `json:"rpcEndpoints"`
+	EthPriceRefreshed int64         `json:"ethPriceRefreshed" example:"1700000000"`
+}
+
+// @Summary Get the status of the indexer
+// @Description Query how far the indexer is behind the chain, when it last dumped successfully, how many events wait in the dead-letter queue, the health of the rpc endpoints and when the eth price was last refreshed. Times are unix seconds, 0 means never. The lag in seconds is the age of the last indexed block.
+// @Tags Chain
+// @Accept json
+// @Produce json
+// @Success 200 {object} Status "return the status successfully"
+// @Failure 500 {object} map[string]string "internal server error"
+// @Router /status [get]
+func GetStatus(d *dumper.Dumper) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// the cursor in the database is the next block to dump, there is none before the first dump
+		blockNumber, err := database.GetBlockNumber()
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		failedEvents, err := database.GetUnresolvedFailedEventAmount()
+		if err != nil {
+			logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		indexedBlock := blockNumber - 1
+		chainHead := d.ChainHead()
+		lagBlocks := int64(0)
+		if chainHead > 0 {
+			lagBlocks = max(int64(chainHead)-indexedBlock, 0)
+		}
+		lagSeconds := int64(0)
+		if d.IndexedTime() > 0 {
+			lagSeconds = max(time.Now().Unix()-int64(d.IndexedTime()), 0)
+		}
+
+		endpoints := make([]RPCEndpoint, 0)
+		for _, e := range d.RPCEndpoints() {
+			endpoints = append(endpoints, RPCEndpoint{
+				URL:     e.URL,
+				Healthy: e.Healthy,
+				Height:  e.Height,
+				InUse:   e.InUse,
+			})
+		}
+
+		c.JSON(http.StatusOK, Status{
+			IndexedBlock:      indexedBlock,
+			ChainHead:         chainHead,
+			LagBlocks:         lagBlocks,
+			LagSeconds:        lagSeconds,
+			LastDump:          d.LastDump(),
+			FailedEvents:      failedEvents,
+			RPCEndpoints:      endpoints,
+			EthPriceRefreshed: dumper.EthUSD_RefreshTime,
+		})
+	}
+}